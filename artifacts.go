@@ -0,0 +1,266 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"time"
+
+	"github.com/pointlander/entity/eda"
+)
+
+// Runs is the directory that holds the run directories
+const Runs = "runs"
+
+// Descriptions describes the artifacts of a run by file name pattern
+var Descriptions = []struct {
+	Pattern     string
+	Description string
+}{
+	{"config.json", "the resolved configuration, seed and build environment of the run"},
+	{"resume-*.json", "the resolved configuration, seed and build environment of a resume of the run"},
+	{"*.checkpoint", "the optimizer checkpoint used to resume the run"},
+	{"*_best.bin", "the best genome as a matrix"},
+	{"*_front.csv", "the pareto front of the objectives and genomes"},
+	{"*_fitness.png", "the best and median fitness per generation"},
+	{"*_elites.gif", "the elites projected on their first two principal components per generation"},
+	{"*_model.bin", "the saved model"},
+	{"inverse_epochs_*.png", "the cost per epoch of fitting the inverse of a gaussian"},
+	{"epochs_*.png", "the cost per epoch of fitting a gaussian"},
+	{"img_*.jpg", "the best image of a generation"},
+	{"*.jsonl", "the per generation metrics"},
+	{"*.csv", "the per generation metrics"},
+}
+
+// artifacts is the directory of the current run
+var artifacts *Artifacts
+
+// Artifacts is the directory that holds the configuration and the outputs of a run
+type Artifacts struct {
+	// Dir is the run directory
+	Dir string
+	// Mode is the mode of the run
+	Mode string
+	// Start is the start of the run
+	Start time.Time
+
+	config Config
+	// name is the file of the configuration, a resume doesn't overwrite the configuration of the run
+	name string
+}
+
+// Config is the resolved configuration of a run
+type Config struct {
+	Mode       string            `json:"mode"`
+	Seed       int64             `json:"seed"`
+	Start      time.Time         `json:"start"`
+	Args       []string          `json:"args"`
+	Flags      map[string]string `json:"flags"`
+	Experiment Experiment        `json:"experiment"`
+	Optimizers []Settings        `json:"optimizers"`
+	Go         string            `json:"go"`
+	OS         string            `json:"os"`
+	Arch       string            `json:"arch"`
+	CPUs       int               `json:"cpus"`
+	Revision   string            `json:"revision,omitempty"`
+}
+
+// Settings are the resolved settings of an optimizer built by a mode
+type Settings struct {
+	Name string `json:"name"`
+	Spec
+	Width   int    `json:"width"`
+	Sampler string `json:"sampler"`
+}
+
+// Manifest describes the files of a run directory
+type Manifest struct {
+	Mode     string     `json:"mode"`
+	Start    time.Time  `json:"start"`
+	Duration string     `json:"duration"`
+	Files    []Artifact `json:"files"`
+}
+
+// Artifact is a file of a run directory
+type Artifact struct {
+	Name        string `json:"name"`
+	Size        int64  `json:"size"`
+	Description string `json:"description"`
+}
+
+// NewArtifacts creates the run directory and writes the configuration, dir
+// defaults to the latest run directory of the mode with a checkpoint when resuming
+// and otherwise to a new directory under Runs. A resume writes its configuration
+// to the next resume-N.json
+func NewArtifacts(dir, mode string) *Artifacts {
+	start := time.Now()
+	if dir == "" && *FlagResume {
+		dir = latest(mode, "*.checkpoint")
+		if dir == "" {
+			panic(fmt.Errorf("no checkpoint of %s found in %s, set -run", mode, Runs))
+		}
+	}
+	if dir == "" {
+		dir = filepath.Join(Runs, fmt.Sprintf("%s_%s_%d", mode, start.Format("20060102-150405"), *FlagSeed))
+	}
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		panic(err)
+	}
+	a := &Artifacts{
+		Dir:   dir,
+		Mode:  mode,
+		Start: start,
+		name:  "config.json",
+	}
+	if *FlagResume {
+		for i := 1; ; i++ {
+			if _, err := os.Stat(a.Path(a.name)); os.IsNotExist(err) {
+				break
+			}
+			a.name = fmt.Sprintf("resume-%d.json", i)
+		}
+	}
+
+	a.config = Config{
+		Mode:       mode,
+		Seed:       *FlagSeed,
+		Start:      start,
+		Args:       os.Args,
+		Flags:      make(map[string]string),
+		Experiment: experiment,
+		Go:         runtime.Version(),
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		CPUs:       runtime.NumCPU(),
+		Optimizers: []Settings{},
+	}
+	flag.VisitAll(func(f *flag.Flag) {
+		a.config.Flags[f.Name] = f.Value.String()
+	})
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				a.config.Revision = setting.Value
+			}
+		}
+	}
+	a.write(a.name, a.config)
+	return a
+}
+
+// Resolve adds the settings of an optimizer built by a mode to the configuration
+func (a *Artifacts) Resolve(optimizer *eda.Optimizer, iterations int) {
+	a.config.Optimizers = append(a.config.Optimizers, Settings{
+		Name: optimizer.Name,
+		Spec: Spec{
+			Population: optimizer.Population,
+			Cut:        optimizer.Cut,
+			Iterations: iterations,
+			Partition:  optimizer.Partition,
+			Cutoff:     optimizer.Cutoff,
+			Eta:        optimizer.Eta,
+		},
+		Width:   optimizer.Width,
+		Sampler: *FlagSampler,
+	})
+	a.write(a.name, a.config)
+}
+
+// Path is the path of a file in the run directory
+func (a *Artifacts) Path(name string) string {
+	return filepath.Join(a.Dir, name)
+}
+
+// write writes a value as indented json to the run directory
+func (a *Artifacts) write(name string, value any) {
+	data, err := json.MarshalIndent(value, "", "\t")
+	if err != nil {
+		panic(err)
+	}
+	err = os.WriteFile(a.Path(name), append(data, '\n'), 0644)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Close writes the manifest of the run directory
+func (a *Artifacts) Close() {
+	manifest := Manifest{
+		Mode:     a.Mode,
+		Start:    a.Start,
+		Duration: time.Since(a.Start).String(),
+		Files:    []Artifact{},
+	}
+	entries, err := os.ReadDir(a.Dir)
+	if err != nil {
+		panic(err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Name() == "manifest.json" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			panic(err)
+		}
+		artifact := Artifact{
+			Name: entry.Name(),
+			Size: info.Size(),
+		}
+		for _, d := range Descriptions {
+			if ok, _ := filepath.Match(d.Pattern, entry.Name()); ok {
+				artifact.Description = d.Description
+				break
+			}
+		}
+		manifest.Files = append(manifest.Files, artifact)
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool {
+		return manifest.Files[i].Name < manifest.Files[j].Name
+	})
+	a.write("manifest.json", manifest)
+}
+
+// Model finds the model file of a mode, the -model flag if set or else the
+// model in the latest run directory of the mode
+func Model(mode string) string {
+	if *FlagModel != "" {
+		return *FlagModel
+	}
+	name := fmt.Sprintf("%s_model.bin", mode)
+	if dir := latest(mode, name); dir != "" {
+		return filepath.Join(dir, name)
+	}
+	panic(fmt.Errorf("no %s found in %s, build it with -%s -build or set -model", name, Runs, mode))
+}
+
+// latest finds the latest run directory of a mode with a file matching pattern,
+// it is empty when there is none
+func latest(mode, pattern string) string {
+	dirs, err := filepath.Glob(filepath.Join(Runs, mode+"_*"))
+	if err != nil {
+		panic(err)
+	}
+	// the names of the run directories sort by start time
+	sort.Sort(sort.Reverse(sort.StringSlice(dirs)))
+	for _, dir := range dirs {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			panic(err)
+		}
+		if len(matches) > 0 {
+			return dir
+		}
+	}
+	return ""
+}
@@ -0,0 +1,135 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/pointlander/entity/eda"
+)
+
+// Problems are the black box test problems of the -dimension and -k flags
+func Problems() ([]eda.Problem, error) {
+	dimension, k := *FlagDimension, *FlagK
+	if dimension < 1 {
+		return nil, fmt.Errorf("the dimension %d of the benchmark problems is less than 1", dimension)
+	}
+	trap, err := eda.Trap(dimension, k)
+	if err != nil {
+		return nil, err
+	}
+	nk, err := eda.NK(rand.New(rand.NewSource(1)), dimension, k)
+	if err != nil {
+		return nil, err
+	}
+	return []eda.Problem{
+		eda.Sphere(dimension),
+		eda.Rosenbrock(dimension),
+		eda.Rastrigin(dimension),
+		eda.Ackley(dimension),
+		eda.OneMax(dimension),
+		trap,
+		nk,
+	}, nil
+}
+
+// BenchOpt runs the optimizer on the black box test problems across seeds
+func BenchOpt(ctx context.Context) {
+	const (
+		population = 256
+		cut        = 64
+	)
+	dimension := *FlagDimension
+	problems, err := Problems()
+	if err != nil {
+		panic(err)
+	}
+	if *FlagProblems != "all" {
+		selected := []eda.Problem{}
+		for _, name := range strings.Split(*FlagProblems, ",") {
+			found := false
+			for _, problem := range problems {
+				if problem.Name == name {
+					selected = append(selected, problem)
+					found = true
+				}
+			}
+			if !found {
+				panic(fmt.Errorf("unknown problem %s", name))
+			}
+		}
+		problems = selected
+	}
+
+	type Result struct {
+		Problem   string
+		Successes int
+		Evals     []int
+		Best      []float64
+	}
+	results := []Result{}
+	for _, problem := range problems {
+		result := Result{Problem: problem.Name}
+		for s := range *FlagSeeds {
+			seed := *FlagSeed + int64(s)
+			source := eda.NewSource(seed)
+			rng := rand.New(source)
+			partition := min(problem.Width, 16)
+			optimizer := eda.NewOptimizer(rng, problem.Width, partition, population, cut, problem.Fitness)
+			optimizer.Name = fmt.Sprintf("%s_%d", problem.Name, seed)
+			optimizer.Problem = problem.Name
+			optimizer.Source = source
+			optimizer.Niching.Distance = eda.Euclidean
+			solved := 0
+			// each benchmark run has its own evaluation budget
+			evaluations = 0
+			Run(ctx, optimizer, *FlagGenerations, func(o *eda.Optimizer) bool {
+				if o.Best().Fitness <= problem.Target {
+					solved = o.Evals
+					return true
+				}
+				return false
+			})
+			if ctx.Err() != nil {
+				return
+			}
+			if solved > 0 {
+				result.Successes++
+				result.Evals = append(result.Evals, solved)
+			}
+			result.Best = append(result.Best, optimizer.Best().Fitness)
+			fmt.Println(problem.Name, "seed", seed, "best", optimizer.Best().Fitness, "evaluations", optimizer.Evals)
+		}
+		results = append(results, result)
+	}
+
+	fmt.Println()
+	fmt.Printf("%-12s %9s %8s %12s %12s %12s\n", "problem", "dimension", "success", "median evals", "mean evals", "median best")
+	for _, result := range results {
+		median := func(values []float64) float64 {
+			if len(values) == 0 {
+				return 0
+			}
+			sorted := append([]float64{}, values...)
+			sort.Float64s(sorted)
+			return sorted[len(sorted)/2]
+		}
+		evals, mean := make([]float64, len(result.Evals)), 0.0
+		for i, value := range result.Evals {
+			evals[i] = float64(value)
+			mean += float64(value) / float64(len(result.Evals))
+		}
+		medianEvals, meanEvals := "-", "-"
+		if len(evals) > 0 {
+			medianEvals, meanEvals = fmt.Sprintf("%.0f", median(evals)), fmt.Sprintf("%.0f", mean)
+		}
+		fmt.Printf("%-12s %9d %7.0f%% %12s %12s %12.4g\n", result.Problem, dimension,
+			100*float64(result.Successes)/float64(*FlagSeeds), medianEvals, meanEvals, median(result.Best))
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"fmt"
+	"math"
+)
+
+// Adaptation grows or shrinks the population and the fraction of elites within
+// bounds from the progress of the best fitness and the spread of the fitted covariance
+type Adaptation struct {
+	// Window is the number of generations progress is measured over, 0 disables adaptation
+	Window int
+	// MinPopulation and MaxPopulation bound the population
+	MinPopulation, MaxPopulation int
+	// MinRatio and MaxRatio bound the fraction of the population that are elites
+	MinRatio, MaxRatio float64
+	// Factor is the factor the population grows or shrinks by
+	Factor float64
+	// Progress is the relative improvement over a window below which the search is stalled
+	Progress float64
+
+	best  []float64
+	trace []float64
+}
+
+// adapt records the last step and returns the reason for a change of the population
+// or the fraction of elites, or an empty string
+func (o *Optimizer) adapt() string {
+	a := &o.Adaptation
+	if a.Window <= 0 {
+		return ""
+	}
+	a.best = append(a.best, o.Diagnostics.Best)
+	a.trace = append(a.trace, o.Diagnostics.Trace)
+	if len(a.best) <= a.Window {
+		return ""
+	}
+	best, trace := a.best[0], a.trace[0]
+	a.best, a.trace = a.best[1:], a.trace[1:]
+
+	progress := (best - o.Diagnostics.Best) / math.Max(math.Abs(best), 1e-12)
+	spread := o.Diagnostics.Trace / math.Max(trace, 1e-12)
+	population, ratio := o.Population, float64(o.Cut)/float64(o.Population)
+	factor := math.Max(a.Factor, 1)
+	reason := ""
+	switch {
+	case progress < a.Progress && spread < .5:
+		// the distribution collapsed without progress, so explore with more and broader elites
+		population, ratio = int(float64(population)*factor), ratio*factor
+		reason = fmt.Sprintf("progress %.3g with the covariance collapsing to %.3g of its trace", progress, spread)
+	case progress < a.Progress:
+		population = int(float64(population) * factor)
+		reason = fmt.Sprintf("progress %.3g over %d generations", progress, a.Window)
+	case spread >= .5:
+		// steady progress with a stable distribution needs fewer samples and more selection pressure
+		population, ratio = int(float64(population)/factor), ratio/factor
+		reason = fmt.Sprintf("progress %.3g with the covariance at %.3g of its trace", progress, spread)
+	default:
+		return ""
+	}
+	if a.MinPopulation > 0 {
+		population = max(population, a.MinPopulation)
+	}
+	if a.MaxPopulation > 0 {
+		population = min(population, a.MaxPopulation)
+	}
+	if a.MinRatio > 0 {
+		ratio = math.Max(ratio, a.MinRatio)
+	}
+	if a.MaxRatio > 0 {
+		ratio = math.Min(ratio, a.MaxRatio)
+	}
+	// the elites are the best of the individuals evaluated in the last step
+	evaluated := 0
+	for _, individual := range o.Pop {
+		if individual.Genome != nil && !math.IsInf(individual.Fitness, 1) {
+			evaluated++
+		}
+	}
+	cut := min(max(int(math.Round(ratio*float64(population))), 1), population-1, evaluated)
+	if population == o.Population && cut == o.Cut {
+		return ""
+	}
+	o.adjust(population, cut)
+	// the next window starts from the new settings
+	a.best, a.trace = nil, nil
+	return reason
+}
+
+// adjust changes the population size and the number of elites, keeping the best
+// individuals of the population as the elites
+func (o *Optimizer) adjust(population, cut int) {
+	pop := make([]Individual, population)
+	copy(pop, o.Pop)
+	o.Pop = pop
+	o.State = make([][]float32, cut)
+	for i := range o.State {
+		o.State[i] = append([]float32{}, o.Pop[i].Genome...)
+	}
+	o.Population, o.Cut = population, cut
+}
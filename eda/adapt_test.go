@@ -0,0 +1,47 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+)
+
+func TestAdaptation(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	optimizer := NewOptimizer(rng, 8, 8, 64, 16, sphere)
+	optimizer.Adaptation = Adaptation{
+		Window:        4,
+		MinPopulation: 32,
+		MaxPopulation: 256,
+		MinRatio:      .1,
+		MaxRatio:      .5,
+		Factor:        2,
+		Progress:      .01,
+	}
+	adaptations := 0
+	optimizer.OnAdapt = func(o *Optimizer, reason string) {
+		adaptations++
+		if o.Population < 32 || o.Population > 256 || o.Cut < 3 || o.Cut > o.Population/2 {
+			t.Fatalf("the population %d and cut %d are out of bounds", o.Population, o.Cut)
+		}
+		if len(o.Pop) != o.Population || len(o.State) != o.Cut {
+			t.Fatalf("the population and the elites were not resized")
+		}
+		for i, elite := range o.State {
+			if Euclidean(elite, o.Pop[i].Genome) != 0 {
+				t.Fatalf("the elites should be the best of the population")
+			}
+		}
+	}
+	optimizer.Run(context.Background(), 64, nil)
+	if adaptations == 0 {
+		t.Fatal("the population was never adapted")
+	}
+	if optimizer.Best().Fitness > 1e-2 {
+		t.Fatalf("the sphere should be solved but the best is %f", optimizer.Best().Fitness)
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"encoding/gob"
+	"fmt"
+	"os"
+)
+
+// CheckpointVersion is the version of the checkpoint file format
+const CheckpointVersion = 1
+
+// Checkpoint is a snapshot of the optimizer state
+type Checkpoint struct {
+	Version     int
+	Name        string
+	Width       int
+	Population  int
+	Cut         int
+	Seed        int64
+	Draws       uint64
+	Generation  int
+	Age         int
+	Evals       int
+	Restarts    int
+	Champion    Individual
+	Diagnostics Diagnostics
+	Optima      []Individual
+	State       [][]float32
+	Pop         []Individual
+	Sampler     []byte
+	// Best and Trace are the window of the adaptation
+	Best, Trace []float64
+	// Genomes and Fitness are the memory of the surrogate
+	Genomes [][]float32
+	Fitness []float64
+}
+
+// IslandsCheckpoint is a snapshot of the islands and their optimizers
+type IslandsCheckpoint struct {
+	Version    int
+	Migrations int
+	Islands    []Checkpoint
+}
+
+// checkpoint takes a snapshot of the optimizer
+func (o *Optimizer) checkpoint() (Checkpoint, error) {
+	checkpoint := Checkpoint{
+		Version:     CheckpointVersion,
+		Name:        o.Name,
+		Width:       o.Width,
+		Population:  o.Population,
+		Cut:         o.Cut,
+		Generation:  o.Generation,
+		Age:         o.Age,
+		Evals:       o.Evals,
+		Restarts:    o.Restarts,
+		Champion:    o.Champion,
+		Diagnostics: o.Diagnostics,
+		State:       o.State,
+		Pop:         o.Pop,
+		Best:        o.Adaptation.best,
+		Trace:       o.Adaptation.trace,
+		Genomes:     o.Screening.genomes,
+		Fitness:     o.Screening.fitness,
+	}
+	if o.Source != nil {
+		checkpoint.Seed, checkpoint.Draws = o.Source.Position()
+	}
+	if o.Archive != nil {
+		checkpoint.Optima = o.Archive.Optima
+	}
+	if sampler, ok := o.Sampler.(StatefulSampler); ok {
+		state, err := sampler.State(o)
+		if err != nil {
+			return checkpoint, err
+		}
+		checkpoint.Sampler = state
+	}
+	return checkpoint, nil
+}
+
+// restore restores the optimizer from a snapshot read from path
+func (o *Optimizer) restore(checkpoint Checkpoint, path string) error {
+	if checkpoint.Version != CheckpointVersion {
+		return fmt.Errorf("checkpoint version %d is not %d", checkpoint.Version, CheckpointVersion)
+	}
+	if checkpoint.Name != o.Name || checkpoint.Width != o.Width {
+		return fmt.Errorf("checkpoint %s is for a different optimizer", path)
+	}
+	// the population grows with IPOP restarts
+	o.Population, o.Cut = checkpoint.Population, checkpoint.Cut
+	o.Generation = checkpoint.Generation
+	o.Age = checkpoint.Age
+	o.Evals = checkpoint.Evals
+	o.Restarts = checkpoint.Restarts
+	o.Champion = checkpoint.Champion
+	o.Diagnostics = checkpoint.Diagnostics
+	o.State = checkpoint.State
+	o.Pop = checkpoint.Pop
+	o.Adaptation.best, o.Adaptation.trace = checkpoint.Best, checkpoint.Trace
+	o.Screening.genomes, o.Screening.fitness = checkpoint.Genomes, checkpoint.Fitness
+	if o.Archive != nil {
+		o.Archive.Optima = checkpoint.Optima
+	}
+	if o.Source != nil {
+		o.Source.Restore(checkpoint.Seed, checkpoint.Draws)
+	}
+	if sampler, ok := o.Sampler.(StatefulSampler); ok && len(checkpoint.Sampler) > 0 {
+		return sampler.Restore(o, checkpoint.Sampler)
+	}
+	return nil
+}
+
+// write gob encodes value to a temporary file that replaces path once it is complete
+func write(path string, value any) error {
+	output, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	err = gob.NewEncoder(output).Encode(value)
+	if err != nil {
+		output.Close()
+		return err
+	}
+	err = output.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// read gob decodes value from path
+func read(path string, value any) error {
+	input, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+	return gob.NewDecoder(input).Decode(value)
+}
+
+// Save writes a checkpoint of the optimizer to path
+func (o *Optimizer) Save(path string) error {
+	checkpoint, err := o.checkpoint()
+	if err != nil {
+		return err
+	}
+	return write(path, &checkpoint)
+}
+
+// Load restores the optimizer from the checkpoint at path
+func (o *Optimizer) Load(path string) error {
+	checkpoint := Checkpoint{}
+	err := read(path, &checkpoint)
+	if err != nil {
+		return err
+	}
+	return o.restore(checkpoint, path)
+}
+
+// Save writes a checkpoint of the islands and the number of migrations to path
+func (is *Islands) Save(path string) error {
+	checkpoint := IslandsCheckpoint{
+		Version:    CheckpointVersion,
+		Migrations: is.Migrations,
+	}
+	for _, island := range is.Islands {
+		c, err := island.checkpoint()
+		if err != nil {
+			return err
+		}
+		checkpoint.Islands = append(checkpoint.Islands, c)
+	}
+	return write(path, &checkpoint)
+}
+
+// Load restores the islands from the checkpoint at path
+func (is *Islands) Load(path string) error {
+	checkpoint := IslandsCheckpoint{}
+	err := read(path, &checkpoint)
+	if err != nil {
+		return err
+	}
+	if checkpoint.Version != CheckpointVersion {
+		return fmt.Errorf("checkpoint version %d is not %d", checkpoint.Version, CheckpointVersion)
+	}
+	if len(checkpoint.Islands) != len(is.Islands) {
+		return fmt.Errorf("checkpoint %s has %d islands instead of %d", path, len(checkpoint.Islands), len(is.Islands))
+	}
+	for i, island := range is.Islands {
+		err := island.restore(checkpoint.Islands[i], path)
+		if err != nil {
+			return err
+		}
+	}
+	is.Migrations = checkpoint.Migrations
+	return nil
+}
@@ -0,0 +1,154 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"context"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sphere.checkpoint")
+	source := NewSource(1)
+	optimizer := NewOptimizer(rand.New(source), 8, 4, 64, 8, sphere)
+	optimizer.Source = source
+	optimizer.Run(context.Background(), 4, nil)
+	err := optimizer.Save(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source = NewSource(1)
+	resumed := NewOptimizer(rand.New(source), 8, 4, 64, 8, sphere)
+	resumed.Source = source
+	err = resumed.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed.Generation != 4 {
+		t.Fatalf("generation should be 4 but is %d", resumed.Generation)
+	}
+	if a, b := optimizer.Rng.Int63(), resumed.Rng.Int63(); a != b {
+		t.Fatalf("rng position was not restored %d != %d", a, b)
+	}
+	for i := range optimizer.Pop {
+		if optimizer.Pop[i].Fitness != resumed.Pop[i].Fitness {
+			t.Fatalf("population differs at %d: %f != %f", i, optimizer.Pop[i].Fitness, resumed.Pop[i].Fitness)
+		}
+	}
+	for i := range optimizer.State {
+		for ii, value := range optimizer.State[i] {
+			if value != resumed.State[i][ii] {
+				t.Fatalf("state differs at %d,%d", i, ii)
+			}
+		}
+	}
+
+	resumed.Name = "other"
+	if resumed.Load(path) == nil {
+		t.Fatal("loading a checkpoint for a different optimizer should fail")
+	}
+}
+
+func TestResume(t *testing.T) {
+	defer func(deterministic bool) {
+		Deterministic = deterministic
+	}(Deterministic)
+	Deterministic = true
+	options := map[string]func(o *Optimizer){
+		"plain": func(o *Optimizer) {},
+		"adaptation": func(o *Optimizer) {
+			o.Adaptation = Adaptation{
+				Window:        2,
+				MinPopulation: 32,
+				MaxPopulation: 256,
+				MinRatio:      .1,
+				MaxRatio:      .5,
+				Factor:        2,
+				Progress:      .5,
+			}
+		},
+		"screening": func(o *Optimizer) {
+			o.Screening = Screening{Surrogate: KNN{K: 4}, Fraction: .5, Memory: 128}
+		},
+	}
+	for name, option := range options {
+		path := filepath.Join(t.TempDir(), "sphere.checkpoint")
+		source := NewSource(1)
+		optimizer := NewOptimizer(rand.New(source), 8, 4, 64, 8, sphere)
+		optimizer.Source = source
+		option(optimizer)
+		optimizer.Run(context.Background(), 6, nil)
+		err := optimizer.Save(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		optimizer.Run(context.Background(), 12, nil)
+
+		source = NewSource(1)
+		resumed := NewOptimizer(rand.New(source), 8, 4, 64, 8, sphere)
+		resumed.Source = source
+		option(resumed)
+		err = resumed.Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resumed.Run(context.Background(), 12, nil)
+		if len(optimizer.Pop) != len(resumed.Pop) || optimizer.Evals != resumed.Evals {
+			t.Fatalf("%s resumed run has %d individuals and %d evaluations instead of %d and %d",
+				name, len(resumed.Pop), resumed.Evals, len(optimizer.Pop), optimizer.Evals)
+		}
+		for i := range optimizer.Pop {
+			if optimizer.Pop[i].Fitness != resumed.Pop[i].Fitness {
+				t.Fatalf("%s resumed run differs at %d: %f != %f", name, i, optimizer.Pop[i].Fitness, resumed.Pop[i].Fitness)
+			}
+		}
+	}
+}
+
+func TestIslandsResume(t *testing.T) {
+	defer func(deterministic bool) {
+		Deterministic = deterministic
+	}(Deterministic)
+	Deterministic = true
+	path := filepath.Join(t.TempDir(), "sphere.checkpoint")
+	create := func() *Islands {
+		source := NewSource(1)
+		optimizer := NewOptimizer(rand.New(source), 8, 4, 64, 8, sphere)
+		optimizer.Name, optimizer.Source = "sphere", source
+		islands := NewIslands(optimizer, 3, nil)
+		islands.Interval = 2
+		return islands
+	}
+	islands := create()
+	islands.Run(context.Background(), 4, nil)
+	err := islands.Save(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	islands.Run(context.Background(), 8, nil)
+
+	resumed := create()
+	err = resumed.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed.Migrations != 2 {
+		t.Fatalf("there should be 2 migrations but there are %d", resumed.Migrations)
+	}
+	resumed.Run(context.Background(), 8, nil)
+	if resumed.Migrations != islands.Migrations {
+		t.Fatalf("the resumed islands migrated %d times instead of %d", resumed.Migrations, islands.Migrations)
+	}
+	for i, island := range islands.Islands {
+		for ii := range island.Pop {
+			if island.Pop[ii].Fitness != resumed.Islands[i].Pop[ii].Fitness {
+				t.Fatalf("resumed island %d differs at %d", i, ii)
+			}
+		}
+	}
+}
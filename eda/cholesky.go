@@ -0,0 +1,72 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"math"
+
+	"github.com/pointlander/entity/matrix"
+)
+
+// Cholesky factors the covariance into a lower triangular A with A*A^T = cov,
+// AI is the transpose of the inverse of A
+func Cholesky[T matrix.Float](invert bool, size int, avg []T, cov [][]T) (A, AI matrix.Matrix[T], u matrix.Matrix[T]) {
+	trace := 0.0
+	for i := range size {
+		trace += float64(cov[i][i])
+	}
+	jitter := 1e-6*trace/float64(size) + 1e-12
+
+	l := make([]float64, size*size)
+	for i := range size {
+		for j := 0; j <= i; j++ {
+			sum := float64(cov[i][j])
+			if i == j {
+				sum += jitter
+			}
+			for k := 0; k < j; k++ {
+				// the conversions prevent the compiler from fusing the multiply and add
+				sum -= float64(l[i*size+k] * l[j*size+k])
+			}
+			if i == j {
+				if sum < jitter {
+					sum = jitter
+				}
+				l[i*size+i] = math.Sqrt(sum)
+			} else {
+				l[i*size+j] = sum / l[j*size+j]
+			}
+		}
+	}
+
+	A = matrix.NewMatrix[T](size, size)
+	for _, value := range l {
+		A.Data = append(A.Data, T(value))
+	}
+	AI = matrix.NewMatrix[T](size, size)
+	if invert {
+		inverse := make([]float64, size*size)
+		for j := range size {
+			for i := j; i < size; i++ {
+				sum := 0.0
+				if i == j {
+					sum = 1
+				}
+				for k := j; k < i; k++ {
+					sum -= float64(l[i*size+k] * inverse[k*size+j])
+				}
+				inverse[i*size+j] = sum / l[i*size+i]
+			}
+		}
+		for i := range size {
+			for j := range size {
+				AI.Data = append(AI.Data, T(inverse[j*size+i]))
+			}
+		}
+	}
+	u = matrix.NewMatrix[T](size, 1)
+	u.Data = append(u.Data, avg...)
+	return A, AI, u
+}
@@ -0,0 +1,34 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"testing"
+)
+
+func TestCholesky(t *testing.T) {
+	cov := [][]float64{
+		{4, 2, 0},
+		{2, 3, 1},
+		{0, 1, 2},
+	}
+	A, AI, _ := Cholesky(true, 3, make([]float64, 3), cov)
+	product := A.MulT(A)
+	for i, value := range product.Data {
+		if diff := value - cov[i/3][i%3]; diff*diff > 1e-9 {
+			t.Fatalf("A*A^T != cov at %d: %f", i, value)
+		}
+	}
+	identity := A.MulT(AI)
+	for i, value := range identity.Data {
+		expected := 0.0
+		if i/3 == i%3 {
+			expected = 1
+		}
+		if diff := value - expected; diff*diff > 1e-9 {
+			t.Fatalf("A*AI^T != I at %d: %f", i, value)
+		}
+	}
+}
@@ -0,0 +1,383 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"bytes"
+	"encoding/gob"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/pointlander/entity/matrix"
+)
+
+// MaxDense is the widest genome the full covariance of CMA is adapted for, the dense
+// matrices and the eigendecomposition grow with the square and the cube of the width
+const MaxDense = 1024
+
+// CMA is a covariance matrix adaptation evolution strategy sampler over the whole genome.
+// The elites are used as the selected parents ranked by fitness, so with the elites carried
+// over between generations it behaves as a plus strategy.
+type CMA struct {
+	// Sigma is the initial step size
+	Sigma float64
+	// Separable adapts only the diagonal of the covariance like sep-CMA-ES, which takes
+	// linear memory and time in the width, it is always used for widths above MaxDense
+	Separable bool
+	// Mean is the mean of the distribution
+	Mean []float64
+	// Step is the step size
+	Step float64
+	// C is the covariance matrix, or its diagonal as a row when separable
+	C matrix.Matrix[float64]
+	// B are the eigenvectors of C as columns, it is empty when separable
+	B matrix.Matrix[float64]
+	// D are the square roots of the eigenvalues of C
+	D []float64
+	// PC is the evolution path of the covariance
+	PC []float64
+	// PS is the evolution path of the step size
+	PS []float64
+	// A is Step*B*D, the sampling transform, or the diagonal Step*D as a row when separable
+	A matrix.Matrix[float32]
+	// U is Mean in single precision
+	U matrix.Matrix[float32]
+
+	updates int
+	eigen   int
+}
+
+// cmaState is the state of CMA in a checkpoint
+type cmaState struct {
+	Mean    []float64
+	Step    float64
+	C, B    matrix.Matrix[float64]
+	D       []float64
+	PC, PS  []float64
+	Updates int
+	Eigen   int
+}
+
+// NewCMA creates a new cma-es sampler
+func NewCMA() *CMA {
+	return &CMA{
+		Sigma: 1,
+	}
+}
+
+// separable is true when only the diagonal of the covariance is adapted for the width n
+func (c *CMA) separable(n int) bool {
+	return c.Separable || n > MaxDense
+}
+
+// identity returns an n by n identity matrix
+func identity(n int) matrix.Matrix[float64] {
+	m := matrix.NewMatrix(n, n, make([]float64, n*n)...)
+	for i := range n {
+		m.Data[i*n+i] = 1
+	}
+	return m
+}
+
+// Jacobi computes the eigenvalues and the eigenvectors, as columns, of a symmetric matrix
+func Jacobi(m matrix.Matrix[float64]) ([]float64, matrix.Matrix[float64]) {
+	n := m.Cols
+	a := matrix.NewMatrix(n, n, append([]float64{}, m.Data...)...)
+	v := identity(n)
+	for range 64 {
+		off, norm := 0.0, 0.0
+		for i := range n {
+			for j := range n {
+				if i != j {
+					off += float64(a.Data[i*n+j] * a.Data[i*n+j])
+				}
+				norm += float64(a.Data[i*n+j] * a.Data[i*n+j])
+			}
+		}
+		if off <= 1e-24*norm {
+			break
+		}
+		for p := 0; p < n-1; p++ {
+			for q := p + 1; q < n; q++ {
+				apq := a.Data[p*n+q]
+				if apq == 0 {
+					continue
+				}
+				theta := (a.Data[q*n+q] - a.Data[p*n+p]) / (2 * apq)
+				t := 1 / (math.Abs(theta) + math.Sqrt(float64(theta*theta)+1))
+				if theta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				// the conversions prevent the compiler from fusing the multiplies and adds
+				rotate := func(x, y float64) (float64, float64) {
+					return float64(c*x) - float64(s*y), float64(s*x) + float64(c*y)
+				}
+				for k := range n {
+					a.Data[k*n+p], a.Data[k*n+q] = rotate(a.Data[k*n+p], a.Data[k*n+q])
+				}
+				for k := range n {
+					a.Data[p*n+k], a.Data[q*n+k] = rotate(a.Data[p*n+k], a.Data[q*n+k])
+				}
+				for k := range n {
+					v.Data[k*n+p], v.Data[k*n+q] = rotate(v.Data[k*n+p], v.Data[k*n+q])
+				}
+			}
+		}
+	}
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = a.Data[i*n+i]
+	}
+	return values, v
+}
+
+// Weights are the rank based weights of mu elites sorted by fitness, they sum to one
+func Weights(mu int) []float64 {
+	weights, sum := make([]float64, mu), 0.0
+	for i := range weights {
+		weights[i] = math.Log(float64(mu)+.5) - math.Log(float64(i+1))
+		sum += weights[i]
+	}
+	for i := range weights {
+		weights[i] /= sum
+	}
+	return weights
+}
+
+// ranked are the Weights of the elites in the order of the state, ranked by fitness because
+// niching can leave the elites out of fitness order, the elites of a reset are ranked in order
+func (o *Optimizer) ranked() []float64 {
+	mu := len(o.State)
+	rank := make([]int, mu)
+	for i := range rank {
+		rank[i] = i
+	}
+	if o.Age > 0 {
+		sort.SliceStable(rank, func(i, j int) bool {
+			return o.Pop[rank[i]].Fitness < o.Pop[rank[j]].Fitness
+		})
+	}
+	weights, ranked := Weights(mu), make([]float64, mu)
+	for i, ii := range rank {
+		ranked[ii] = weights[i]
+	}
+	return ranked
+}
+
+// Fit updates the mean, the evolution paths, the covariance and the step size from the elites
+func (c *CMA) Fit(o *Optimizer) {
+	n, mu := o.Width, len(o.State)
+	if o.Age == 0 || len(c.Mean) != n {
+		c.Mean = make([]float64, n)
+		for _, elite := range o.State {
+			for i, value := range elite {
+				c.Mean[i] += float64(value) / float64(mu)
+			}
+		}
+		c.Step = c.Sigma
+		if c.separable(n) {
+			c.C, c.B = matrix.NewMatrix(n, 1, make([]float64, n)...), matrix.Matrix[float64]{}
+			for i := range c.C.Data {
+				c.C.Data[i] = 1
+			}
+		} else {
+			c.C, c.B = identity(n), identity(n)
+		}
+		c.D = make([]float64, n)
+		for i := range c.D {
+			c.D[i] = 1
+		}
+		c.PC, c.PS = make([]float64, n), make([]float64, n)
+		c.updates, c.eigen = 0, 0
+		c.transform()
+		return
+	}
+
+	// the products are converted so the compiler does not fuse the multiplies and adds
+	weights, mueff := o.ranked(), 0.0
+	for i := range weights {
+		mueff += float64(weights[i] * weights[i])
+	}
+	mueff = 1 / mueff
+	N := float64(n)
+	cc := (4 + mueff/N) / (N + 4 + 2*mueff/N)
+	cs := (mueff + 2) / (N + mueff + 5)
+	c1 := 2 / (float64((N+1.3)*(N+1.3)) + mueff)
+	cmu := math.Min(1-c1, 2*(mueff-2+1/mueff)/(float64((N+2)*(N+2))+mueff))
+	damps := 1 + float64(2*math.Max(0, math.Sqrt((mueff-1)/(N+1))-1)) + cs
+	separable := c.separable(n)
+	if separable {
+		// the diagonal has fewer parameters so it is learned faster
+		c1 *= (N + 2) / 3
+		cmu = math.Min(1-c1, cmu*(N+2)/3)
+	}
+	chi := math.Sqrt(N) * (1 - 1/(4*N) + 1/(21*N*N))
+
+	y := make([][]float64, mu)
+	yw := make([]float64, n)
+	for i, elite := range o.State {
+		y[i] = make([]float64, n)
+		for ii, value := range elite {
+			y[i][ii] = (float64(value) - c.Mean[ii]) / c.Step
+			yw[ii] += float64(weights[i] * y[i][ii])
+		}
+	}
+	for i := range c.Mean {
+		c.Mean[i] += float64(c.Step * yw[i])
+	}
+
+	// C^-1/2 yw = B D^-1 B^T yw
+	z := make([]float64, n)
+	for j := range n {
+		if separable {
+			z[j] = yw[j] / c.D[j]
+			continue
+		}
+		s := 0.0
+		for i := range n {
+			s += float64(c.B.Data[i*n+j] * yw[i])
+		}
+		z[j] = s / c.D[j]
+	}
+	norm := 0.0
+	for i := range n {
+		s := z[i]
+		if !separable {
+			s = 0
+			for j := range n {
+				s += float64(c.B.Data[i*n+j] * z[j])
+			}
+		}
+		c.PS[i] = float64((1-cs)*c.PS[i]) + float64(math.Sqrt(cs*(2-cs)*mueff)*s)
+		norm += float64(c.PS[i] * c.PS[i])
+	}
+	norm = math.Sqrt(norm)
+	c.updates++
+	hsig := 0.0
+	if norm/math.Sqrt(1-math.Pow(1-cs, 2*float64(c.updates)))/chi < 1.4+2/(N+1) {
+		hsig = 1
+	}
+	for i := range c.PC {
+		c.PC[i] = float64((1-cc)*c.PC[i]) + float64(hsig*math.Sqrt(cc*(2-cc)*mueff)*yw[i])
+	}
+	if separable {
+		for i := range n {
+			rank := 0.0
+			for k := range y {
+				rank += float64(weights[k] * y[k][i] * y[k][i])
+			}
+			c.C.Data[i] = float64((1-c1-cmu)*c.C.Data[i]) +
+				float64(c1*(float64(c.PC[i]*c.PC[i])+float64((1-hsig)*cc*(2-cc)*c.C.Data[i]))) +
+				float64(cmu*rank)
+			c.D[i] = math.Sqrt(math.Max(c.C.Data[i], 1e-20))
+		}
+		c.Step *= math.Exp((cs / damps) * (norm/chi - 1))
+		c.transform()
+		return
+	}
+	for i := range n {
+		for j := range n {
+			rank := 0.0
+			for k := range y {
+				rank += float64(weights[k] * y[k][i] * y[k][j])
+			}
+			c.C.Data[i*n+j] = float64((1-c1-cmu)*c.C.Data[i*n+j]) +
+				float64(c1*(float64(c.PC[i]*c.PC[j])+float64((1-hsig)*cc*(2-cc)*c.C.Data[i*n+j]))) +
+				float64(cmu*rank)
+		}
+	}
+	c.Step *= math.Exp((cs / damps) * (norm/chi - 1))
+
+	if float64(c.updates-c.eigen) > 1/((c1+cmu)*N*10) {
+		c.eigen = c.updates
+		values, vectors := Jacobi(c.C)
+		for i, value := range values {
+			c.D[i] = math.Sqrt(math.Max(value, 1e-20))
+		}
+		c.B = vectors
+	}
+	c.transform()
+}
+
+// State encodes the distribution, the evolution paths and the update counters
+func (c *CMA) State(o *Optimizer) ([]byte, error) {
+	buffer := bytes.Buffer{}
+	err := gob.NewEncoder(&buffer).Encode(cmaState{
+		Mean:    c.Mean,
+		Step:    c.Step,
+		C:       c.C,
+		B:       c.B,
+		D:       c.D,
+		PC:      c.PC,
+		PS:      c.PS,
+		Updates: c.updates,
+		Eigen:   c.eigen,
+	})
+	return buffer.Bytes(), err
+}
+
+// Restore decodes the distribution, the evolution paths and the update counters
+func (c *CMA) Restore(o *Optimizer, state []byte) error {
+	s := cmaState{}
+	err := gob.NewDecoder(bytes.NewReader(state)).Decode(&s)
+	if err != nil {
+		return err
+	}
+	c.Mean, c.Step = s.Mean, s.Step
+	c.C, c.B, c.D = s.C, s.B, s.D
+	c.PC, c.PS = s.PC, s.PS
+	c.updates, c.eigen = s.Updates, s.Eigen
+	c.transform()
+	return nil
+}
+
+// transform computes the single precision sampling transform
+func (c *CMA) transform() {
+	n := len(c.Mean)
+	if c.separable(n) {
+		c.A = matrix.NewMatrix[float32](n, 1)
+		for _, d := range c.D {
+			c.A.Data = append(c.A.Data, float32(c.Step*d))
+		}
+	} else {
+		c.A = matrix.NewMatrix[float32](n, n)
+		for i := range n {
+			for j := range n {
+				c.A.Data = append(c.A.Data, float32(c.Step*c.B.Data[i*n+j]*c.D[j]))
+			}
+		}
+	}
+	c.U = matrix.NewMatrix[float32](n, 1)
+	for _, value := range c.Mean {
+		c.U.Data = append(c.U.Data, float32(value))
+	}
+}
+
+// Sample samples the mean plus the transformed standard normal
+func (c *CMA) Sample(rng *rand.Rand, genome []float32) {
+	if c.separable(len(c.Mean)) {
+		for i, scale := range c.A.Data {
+			genome[i] = c.U.Data[i] + float32(scale*float32(rng.NormFloat64()))
+		}
+		return
+	}
+	g := matrix.NewMatrix[float32](c.A.Cols, 1)
+	for range c.A.Cols {
+		g.Data = append(g.Data, float32(rng.NormFloat64()))
+	}
+	copy(genome, c.A.MulT(g).Add(c.U).Data)
+}
+
+// Trace is the trace of the scaled covariance
+func (c *CMA) Trace() float64 {
+	trace := 0.0
+	for _, d := range c.D {
+		trace += float64(d * d)
+	}
+	return c.Step * c.Step * trace
+}
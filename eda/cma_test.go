@@ -0,0 +1,115 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/pointlander/entity/matrix"
+)
+
+func TestCMA(t *testing.T) {
+	m := matrix.NewMatrix(3, 3, 4.0, 1, 2, 1, 3, 0, 2, 0, 5)
+	values, vectors := Jacobi(m)
+	for j, value := range values {
+		for i := range 3 {
+			s := 0.0
+			for k := range 3 {
+				s += m.Data[i*3+k] * vectors.Data[k*3+j]
+			}
+			if math.Abs(s-value*vectors.Data[i*3+j]) > 1e-9 {
+				t.Fatalf("%f is not an eigenvalue", value)
+			}
+		}
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	optimizer := NewOptimizer(rng, 8, 8, 32, 8, func(g []float32) float64 {
+		fitness := 0.0
+		for i, value := range g {
+			diff := float64(value) - float64(i)
+			fitness += diff * diff
+		}
+		return fitness
+	})
+	optimizer.Sampler = NewCMA()
+	optimizer.Run(context.Background(), 256, nil)
+	if best := optimizer.Best().Fitness; best > 1e-6 {
+		t.Fatalf("cma-es should converge but the best is %f", best)
+	}
+}
+
+func TestCMAResume(t *testing.T) {
+	shifted := func(g []float32) float64 {
+		fitness := 0.0
+		for i, value := range g {
+			diff := float64(value) - float64(i)
+			fitness += diff * diff
+		}
+		return fitness
+	}
+	path := filepath.Join(t.TempDir(), "cma.checkpoint")
+	source := NewSource(1)
+	optimizer := NewOptimizer(rand.New(source), 8, 8, 32, 8, shifted)
+	optimizer.Source = source
+	optimizer.Sampler = NewCMA()
+	optimizer.Run(context.Background(), 4, nil)
+	err := optimizer.Save(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	optimizer.Run(context.Background(), 8, nil)
+
+	source = NewSource(1)
+	resumed := NewOptimizer(rand.New(source), 8, 8, 32, 8, shifted)
+	resumed.Source = source
+	cma := NewCMA()
+	resumed.Sampler = cma
+	err = resumed.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cma.updates != 3 || len(cma.A.Data) != 64 {
+		t.Fatalf("the distribution was not restored after %d updates", cma.updates)
+	}
+	resumed.Run(context.Background(), 8, nil)
+	for i := range optimizer.Pop {
+		if optimizer.Pop[i].Fitness != resumed.Pop[i].Fitness {
+			t.Fatalf("resumed run differs at %d: %f != %f", i, optimizer.Pop[i].Fitness, resumed.Pop[i].Fitness)
+		}
+	}
+}
+
+func TestCMASeparable(t *testing.T) {
+	shifted := func(g []float32) float64 {
+		fitness := 0.0
+		for i, value := range g {
+			diff := float64(value) - float64(i%8)
+			fitness += diff * diff
+		}
+		return fitness
+	}
+	rng := rand.New(rand.NewSource(1))
+	optimizer := NewOptimizer(rng, 8, 8, 32, 8, shifted)
+	optimizer.Sampler = &CMA{Sigma: 1, Separable: true}
+	optimizer.Run(context.Background(), 256, nil)
+	if best := optimizer.Best().Fitness; best > 1e-6 {
+		t.Fatalf("sep-cma-es should converge but the best is %f", best)
+	}
+
+	// the covariance of a wide genome is diagonal
+	width := 4 * MaxDense
+	cma := NewCMA()
+	optimizer = NewOptimizer(rng, width, width, 16, 4, shifted)
+	optimizer.Sampler = cma
+	optimizer.Run(context.Background(), 4, nil)
+	if len(cma.C.Data) != width || len(cma.A.Data) != width {
+		t.Fatalf("the covariance of width %d has %d entries", width, len(cma.C.Data))
+	}
+}
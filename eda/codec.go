@@ -0,0 +1,201 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"math"
+	"math/bits"
+	"sort"
+)
+
+// Codec maps a span of the genome to a typed value and back
+type Codec[T any] interface {
+	// Width is the number of genome positions used
+	Width() int
+	// Decode decodes the value from the genome
+	Decode(g []float32) T
+	// Encode encodes the value into the genome
+	Encode(value T, g []float32)
+}
+
+// Decoded adapts a fitness function of the decoded value to a Fitness
+func Decoded[T any](codec Codec[T], fitness func(value T) float64) Fitness {
+	return func(g []float32) float64 {
+		return fitness(codec.Decode(g))
+	}
+}
+
+// Bits is a codec for bits, a positive gene is set
+type Bits struct {
+	N int
+}
+
+// Width is the number of bits
+func (b Bits) Width() int {
+	return b.N
+}
+
+// Decode decodes the bits
+func (b Bits) Decode(g []float32) []bool {
+	value := make([]bool, b.N)
+	for i := range value {
+		value[i] = g[i] > 0
+	}
+	return value
+}
+
+// Encode encodes the bits
+func (b Bits) Encode(value []bool, g []float32) {
+	for i, bit := range value {
+		if bit {
+			g[i] = 1
+		} else {
+			g[i] = -1
+		}
+	}
+}
+
+// Int is a codec for an integer in [Min, Max] stored most significant bit first
+type Int struct {
+	Min, Max int
+}
+
+// Width is the number of bits needed for the range
+func (i Int) Width() int {
+	return bits.Len(uint(i.Max - i.Min))
+}
+
+// Decode decodes the integer, values past the range wrap around
+func (i Int) Decode(g []float32) int {
+	y := 0
+	for _, gene := range g[:i.Width()] {
+		y <<= 1
+		if gene > 0 {
+			y |= 1
+		}
+	}
+	return i.Min + y%(i.Max-i.Min+1)
+}
+
+// Encode encodes the integer
+func (i Int) Encode(value int, g []float32) {
+	y, width := value-i.Min, i.Width()
+	for ii := range width {
+		if y&(1<<(width-ii-1)) != 0 {
+			g[ii] = 1
+		} else {
+			g[ii] = -1
+		}
+	}
+}
+
+// Categorical is a codec for a choice among values
+type Categorical[T comparable] struct {
+	Choices []T
+}
+
+func (c Categorical[T]) index() Int {
+	return Int{Min: 0, Max: len(c.Choices) - 1}
+}
+
+// Width is the number of bits needed for the choices
+func (c Categorical[T]) Width() int {
+	return c.index().Width()
+}
+
+// Decode decodes the choice
+func (c Categorical[T]) Decode(g []float32) T {
+	return c.Choices[c.index().Decode(g)]
+}
+
+// Encode encodes the choice
+func (c Categorical[T]) Encode(value T, g []float32) {
+	for i, choice := range c.Choices {
+		if choice == value {
+			c.index().Encode(i, g)
+			return
+		}
+	}
+	panic("value is not a choice")
+}
+
+// Permutation is a random key codec for a permutation of [0, N)
+type Permutation struct {
+	N int
+}
+
+// Width is the number of keys
+func (p Permutation) Width() int {
+	return p.N
+}
+
+// Decode decodes the permutation by sorting the keys
+func (p Permutation) Decode(g []float32) []int {
+	value := make([]int, p.N)
+	for i := range value {
+		value[i] = i
+	}
+	sort.SliceStable(value, func(i, j int) bool {
+		return g[value[i]] < g[value[j]]
+	})
+	return value
+}
+
+// Encode encodes the permutation as evenly spaced keys
+func (p Permutation) Encode(value []int, g []float32) {
+	for i, v := range value {
+		g[v] = float32(2*i-p.N+1) / float32(p.N)
+	}
+}
+
+// Real is a codec for a real value in (Min, Max) through the logistic function
+type Real struct {
+	Min, Max float64
+}
+
+// Width is one gene
+func (r Real) Width() int {
+	return 1
+}
+
+// Decode decodes the real value
+func (r Real) Decode(g []float32) float64 {
+	return r.Min + (r.Max-r.Min)/(1+math.Exp(-float64(g[0])))
+}
+
+// Encode encodes the real value
+func (r Real) Encode(value float64, g []float32) {
+	p := (value - r.Min) / (r.Max - r.Min)
+	g[0] = float32(math.Log(p / (1 - p)))
+}
+
+// Repeat is a codec for N consecutive values
+type Repeat[T any] struct {
+	Codec Codec[T]
+	N     int
+}
+
+// Width is the total width of the values
+func (r Repeat[T]) Width() int {
+	return r.N * r.Codec.Width()
+}
+
+// Decode decodes the values
+func (r Repeat[T]) Decode(g []float32) []T {
+	width := r.Codec.Width()
+	value := make([]T, r.N)
+	for i := range value {
+		value[i] = r.Codec.Decode(g[i*width : (i+1)*width])
+	}
+	return value
+}
+
+// Encode encodes the values
+func (r Repeat[T]) Encode(value []T, g []float32) {
+	width := r.Codec.Width()
+	for i, v := range value {
+		r.Codec.Encode(v, g[i*width:(i+1)*width])
+	}
+}
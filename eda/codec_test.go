@@ -0,0 +1,101 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestCodec(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	random := func(width int) []float32 {
+		g := make([]float32, width)
+		for i := range g {
+			g[i] = float32(rng.NormFloat64())
+		}
+		return g
+	}
+
+	queens := Repeat[int]{Codec: Int{Min: 0, Max: 7}, N: 8}
+	if queens.Width() != 24 {
+		t.Fatalf("the width should be 24 but is %d", queens.Width())
+	}
+	g := random(queens.Width())
+	value := queens.Decode(g)
+	queens.Encode(value, g)
+	for i, v := range queens.Decode(g) {
+		if v != value[i] || v < 0 || v > 7 {
+			t.Fatalf("wrong queen %d %d", v, value[i])
+		}
+	}
+
+	ints := Int{Min: -3, Max: 9}
+	g = random(ints.Width())
+	for v := ints.Min; v <= ints.Max; v++ {
+		ints.Encode(v, g)
+		if decoded := ints.Decode(g); decoded != v {
+			t.Fatalf("%d decoded to %d", v, decoded)
+		}
+	}
+
+	genes := Categorical[rune]{Choices: []rune("+-<>.[]")}
+	g = random(genes.Width())
+	for _, gene := range genes.Choices {
+		genes.Encode(gene, g)
+		if decoded := genes.Decode(g); decoded != gene {
+			t.Fatalf("%c decoded to %c", gene, decoded)
+		}
+	}
+
+	permutation := Permutation{N: 16}
+	g = random(permutation.Width())
+	order := permutation.Decode(g)
+	seen := make([]bool, permutation.N)
+	for _, v := range order {
+		seen[v] = true
+	}
+	for i, v := range seen {
+		if !v {
+			t.Fatalf("%d is missing from the permutation", i)
+		}
+	}
+	g = random(permutation.Width())
+	permutation.Encode(order, g)
+	for i, v := range permutation.Decode(g) {
+		if v != order[i] {
+			t.Fatalf("wrong permutation %v %v", permutation.Decode(g), order)
+		}
+	}
+
+	real := Real{Min: -2, Max: 5}
+	g = random(real.Width())
+	if v := real.Decode(g); v <= real.Min || v >= real.Max {
+		t.Fatalf("%f is out of bounds", v)
+	}
+	real.Encode(1.5, g)
+	if v := real.Decode(g); math.Abs(v-1.5) > 1e-5 {
+		t.Fatalf("1.5 decoded to %f", v)
+	}
+
+	bits := Bits{N: 16}
+	optimizer := NewOptimizer(rng, bits.Width(), bits.Width(), 256, 32, Decoded(bits, func(value []bool) float64 {
+		fitness := 0.0
+		for _, bit := range value {
+			if !bit {
+				fitness++
+			}
+		}
+		return fitness
+	}))
+	optimizer.Run(context.Background(), 32, func(o *Optimizer) bool {
+		return o.Best().Fitness == 0
+	})
+	if best := optimizer.Best().Fitness; best != 0 {
+		t.Fatalf("onemax should be solved but the best is %f", best)
+	}
+}
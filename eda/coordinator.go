@@ -0,0 +1,176 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned when the coordinator is closed during an evaluation
+var ErrClosed = errors.New("coordinator closed")
+
+// remote is a worker connected over tcp
+type remote struct {
+	*worker
+	conn net.Conn
+}
+
+// Coordinator is an Evaluator that hands batches of genomes to workers connected over
+// tcp with the line delimited json protocol of Subprocess. A batch is reassigned to
+// another worker when its worker is lost.
+type Coordinator struct {
+	// Batch is the maximum number of genomes in a request
+	Batch int
+	// Timeout is the longest a worker may take for a batch before it is considered lost, 0 waits forever
+	Timeout time.Duration
+	// OnWorker is called when a worker connects or is lost
+	OnWorker func(addr string, connected bool)
+
+	listener net.Listener
+	workers  chan *remote
+	mutex    sync.Mutex
+	all      map[*remote]bool
+	closed   chan struct{}
+	once     sync.Once
+}
+
+// NewCoordinator listens for workers on the tcp address
+func NewCoordinator(addr string, batch int) (*Coordinator, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &Coordinator{
+		Batch:    batch,
+		listener: listener,
+		workers:  make(chan *remote, 1024),
+		all:      make(map[*remote]bool),
+		closed:   make(chan struct{}),
+	}
+	go c.accept()
+	return c, nil
+}
+
+// Addr is the address the coordinator listens on
+func (c *Coordinator) Addr() net.Addr {
+	return c.listener.Addr()
+}
+
+// accept accepts workers until the listener is closed
+func (c *Coordinator) accept() {
+	for {
+		conn, err := c.listener.Accept()
+		if err != nil {
+			return
+		}
+		r := &remote{
+			worker: newWorker(conn, conn),
+			conn:   conn,
+		}
+		c.mutex.Lock()
+		c.all[r] = true
+		c.mutex.Unlock()
+		if c.OnWorker != nil {
+			c.OnWorker(conn.RemoteAddr().String(), true)
+		}
+		c.workers <- r
+	}
+}
+
+// lose closes the connection of a lost worker
+func (c *Coordinator) lose(r *remote) {
+	r.conn.Close()
+	c.mutex.Lock()
+	delete(c.all, r)
+	c.mutex.Unlock()
+	if c.OnWorker != nil {
+		c.OnWorker(r.conn.RemoteAddr().String(), false)
+	}
+}
+
+// Workers is the number of connected workers
+func (c *Coordinator) Workers() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.all)
+}
+
+// Evaluate splits the genomes into batches and evaluates them on the idle workers,
+// waiting for a worker to connect when there are none until the coordinator is closed
+func (c *Coordinator) Evaluate(problem string, genomes [][]float32) ([]float64, error) {
+	type Batch struct {
+		Begin, End int
+	}
+	fitness := make([]float64, len(genomes))
+	size := max(c.Batch, 1)
+	batches := (len(genomes) + size - 1) / size
+	pending, done := make(chan Batch, batches), make(chan error, batches)
+	for i := 0; i < len(genomes); i += size {
+		pending <- Batch{Begin: i, End: min(i+size, len(genomes))}
+	}
+	var errs error
+	for remaining := batches; remaining > 0; {
+		select {
+		case batch := <-pending:
+			var r *remote
+			select {
+			case r = <-c.workers:
+			case <-c.closed:
+				return nil, ErrClosed
+			}
+			go func() {
+				if c.Timeout > 0 {
+					r.conn.SetDeadline(time.Now().Add(c.Timeout))
+				}
+				values, err := r.evaluate(problem, genomes[batch.Begin:batch.End])
+				if errors.Is(err, ErrLost) {
+					c.lose(r)
+					pending <- batch
+					return
+				}
+				c.workers <- r
+				if err == nil {
+					copy(fitness[batch.Begin:batch.End], values)
+				}
+				done <- err
+			}()
+		case err := <-done:
+			errs = errors.Join(errs, err)
+			remaining--
+		case <-c.closed:
+			return nil, ErrClosed
+		}
+	}
+	return fitness, errs
+}
+
+// Close stops listening, disconnects the workers and ends the evaluations in progress
+func (c *Coordinator) Close() error {
+	var err error
+	c.once.Do(func() {
+		close(c.closed)
+		err = c.listener.Close()
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		for r := range c.all {
+			r.conn.Close()
+		}
+	})
+	return err
+}
+
+// Work connects to a coordinator and evaluates the batches it sends until the
+// connection is closed
+func Work(addr string, problems func(name string) (Fitness, error)) error {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return Serve(conn, conn, problems)
+}
@@ -0,0 +1,103 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestCoordinator(t *testing.T) {
+	coordinator, err := NewCoordinator("127.0.0.1:0", 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	workers := []*exec.Cmd{}
+	defer func() {
+		coordinator.Close()
+		for _, worker := range workers {
+			worker.Wait()
+		}
+	}()
+	lost := make(chan bool, 8)
+	coordinator.OnWorker = func(addr string, connected bool) {
+		if !connected {
+			lost <- true
+		}
+	}
+	start := func(flaky bool) {
+		cmd := exec.Command(os.Args[0])
+		cmd.Env = append(os.Environ(), "EDA_COORDINATOR="+coordinator.Addr().String())
+		if flaky {
+			cmd.Env = append(cmd.Env, "EDA_FLAKY=1")
+		}
+		err := cmd.Start()
+		if err != nil {
+			t.Fatal(err)
+		}
+		workers = append(workers, cmd)
+	}
+	start(true)
+	for coordinator.Workers() < 1 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	start(false)
+	start(false)
+
+	rng := rand.New(rand.NewSource(1))
+	optimizer := NewOptimizer(rng, 4, 4, 64, 16, nil)
+	optimizer.Name = "sphere"
+	optimizer.Evaluator = coordinator
+	err = optimizer.Run(context.Background(), 4, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, individual := range optimizer.Pop {
+		if individual.Fitness != sphere(individual.Genome) {
+			t.Fatalf("wrong fitness %f != %f", individual.Fitness, sphere(individual.Genome))
+		}
+	}
+	select {
+	case <-lost:
+	default:
+		t.Fatal("the flaky worker should have been lost")
+	}
+	if workers := coordinator.Workers(); workers != 2 {
+		t.Fatalf("there should be 2 workers but there are %d", workers)
+	}
+	optimizer.Problem = "rastrigin"
+	err = optimizer.Run(context.Background(), 8, nil)
+	if !errors.Is(err, ErrWorker) || errors.Is(err, ErrLost) {
+		t.Fatalf("an unknown problem should fail the run but got %v", err)
+	}
+}
+
+func TestCoordinatorClose(t *testing.T) {
+	coordinator, err := NewCoordinator("127.0.0.1:0", 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	context.AfterFunc(ctx, func() {
+		coordinator.Close()
+	})
+	rng := rand.New(rand.NewSource(1))
+	optimizer := NewOptimizer(rng, 4, 4, 64, 16, nil)
+	optimizer.Name = "sphere"
+	optimizer.Evaluator = coordinator
+	time.AfterFunc(10*time.Millisecond, cancel)
+	err = optimizer.Run(ctx, 4, nil)
+	if !errors.Is(err, ErrClosed) {
+		t.Fatalf("closing the coordinator without workers should end the run but got %v", err)
+	}
+	if err := coordinator.Close(); err != nil {
+		t.Fatalf("closing twice should not fail: %v", err)
+	}
+}
@@ -0,0 +1,291 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+)
+
+// Evaluator evaluates batches of genomes outside of the fitness functions
+type Evaluator interface {
+	// Evaluate returns the fitness of each genome for the named problem
+	Evaluate(problem string, genomes [][]float32) ([]float64, error)
+}
+
+// Request is a batch of genomes sent to an evaluator as one line of json
+type Request struct {
+	ID      int         `json:"id"`
+	Problem string      `json:"problem"`
+	Genomes [][]float32 `json:"genomes"`
+}
+
+// Response is the fitness of a batch returned by an evaluator as one line of json
+type Response struct {
+	ID      int     `json:"id"`
+	Fitness []Value `json:"fitness"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// Value is a fitness in a response, json has no numbers for the non-finite values
+// so they are the strings "NaN", "+Inf" and "-Inf"
+type Value float64
+
+// MarshalJSON encodes the value as a number or as a string when it isn't finite
+func (v Value) MarshalJSON() ([]byte, error) {
+	value := float64(v)
+	switch {
+	case math.IsNaN(value):
+		return []byte(`"NaN"`), nil
+	case math.IsInf(value, 1):
+		return []byte(`"+Inf"`), nil
+	case math.IsInf(value, -1):
+		return []byte(`"-Inf"`), nil
+	}
+	return json.Marshal(value)
+}
+
+// UnmarshalJSON decodes a number or a string
+func (v *Value) UnmarshalJSON(data []byte) error {
+	var value float64
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		err := json.Unmarshal(data, &s)
+		if err != nil {
+			return err
+		}
+		value, err = strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+	} else {
+		err := json.Unmarshal(data, &value)
+		if err != nil {
+			return err
+		}
+	}
+	*v = Value(value)
+	return nil
+}
+
+var (
+	// ErrWorker is returned when an evaluator worker fails
+	ErrWorker = errors.New("evaluator worker failed")
+	// ErrLost is returned when the connection to an evaluator worker is lost
+	ErrLost = fmt.Errorf("%w: connection lost", ErrWorker)
+)
+
+// worker is a child process or a connection speaking the line delimited json protocol
+type worker struct {
+	cmd    *exec.Cmd
+	input  io.WriteCloser
+	output *bufio.Scanner
+	id     int
+}
+
+// newWorker creates a worker from the two ends of a connection
+func newWorker(input io.WriteCloser, output io.Reader) *worker {
+	scanner := bufio.NewScanner(output)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024*1024)
+	return &worker{
+		input:  input,
+		output: scanner,
+	}
+}
+
+// evaluate sends a batch to the worker and reads back the fitness
+func (w *worker) evaluate(problem string, genomes [][]float32) ([]float64, error) {
+	w.id++
+	data, err := json.Marshal(Request{ID: w.id, Problem: problem, Genomes: genomes})
+	if err != nil {
+		return nil, err
+	}
+	_, err = w.input.Write(append(data, '\n'))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLost, err)
+	}
+	if !w.output.Scan() {
+		err := w.output.Err()
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("%w: %v", ErrLost, err)
+	}
+	var response Response
+	err = json.Unmarshal(w.output.Bytes(), &response)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLost, err)
+	}
+	switch {
+	case response.Error != "":
+		return nil, fmt.Errorf("%w: %s", ErrWorker, response.Error)
+	case response.ID != w.id:
+		return nil, fmt.Errorf("%w: response %d to request %d", ErrLost, response.ID, w.id)
+	case len(response.Fitness) != len(genomes):
+		return nil, fmt.Errorf("%w: %d fitness values for %d genomes", ErrWorker, len(response.Fitness), len(genomes))
+	}
+	fitness := make([]float64, len(response.Fitness))
+	for i, value := range response.Fitness {
+		fitness[i] = float64(value)
+	}
+	return fitness, nil
+}
+
+// Subprocess is a pool of long running child processes that evaluate batches of
+// genomes. Each line written to the stdin of a child is a Request and each line it
+// writes to stdout is the matching Response. A lost child is restarted before it
+// is sent another batch.
+type Subprocess struct {
+	// Batch is the maximum number of genomes in a request
+	Batch int
+
+	command string
+	workers chan *worker
+	all     []*worker
+}
+
+// NewSubprocess starts workers copies of the command, which is run by the shell
+func NewSubprocess(command string, workers, batch int) (*Subprocess, error) {
+	s := &Subprocess{
+		Batch:   batch,
+		command: command,
+		workers: make(chan *worker, workers),
+	}
+	for range workers {
+		w := &worker{}
+		err := s.start(w)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.all = append(s.all, w)
+		s.workers <- w
+	}
+	return s, nil
+}
+
+// start starts a new child process for the worker
+func (s *Subprocess) start(w *worker) error {
+	cmd := exec.Command("sh", "-c", s.command)
+	cmd.Stderr = os.Stderr
+	input, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	output, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	err = cmd.Start()
+	if err != nil {
+		return err
+	}
+	*w = *newWorker(input, output)
+	w.cmd = cmd
+	return nil
+}
+
+// evaluate evaluates a batch on the worker, the child process of a lost worker is
+// killed and a new one is started the next time the worker is used
+func (s *Subprocess) evaluate(w *worker, problem string, genomes [][]float32) ([]float64, error) {
+	if w.cmd == nil {
+		err := s.start(w)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrLost, err)
+		}
+	}
+	values, err := w.evaluate(problem, genomes)
+	if errors.Is(err, ErrLost) {
+		w.input.Close()
+		w.cmd.Process.Kill()
+		w.cmd.Wait()
+		w.cmd = nil
+	}
+	return values, err
+}
+
+// Evaluate splits the genomes into batches and evaluates them on the idle workers
+func (s *Subprocess) Evaluate(problem string, genomes [][]float32) ([]float64, error) {
+	fitness := make([]float64, len(genomes))
+	batch := max(s.Batch, 1)
+	var (
+		wait  sync.WaitGroup
+		mutex sync.Mutex
+		errs  error
+	)
+	for i := 0; i < len(genomes); i += batch {
+		end := min(i+batch, len(genomes))
+		w := <-s.workers
+		wait.Add(1)
+		go func(i, end int) {
+			defer wait.Done()
+			values, err := s.evaluate(w, problem, genomes[i:end])
+			s.workers <- w
+			if err != nil {
+				mutex.Lock()
+				errs = errors.Join(errs, err)
+				mutex.Unlock()
+				return
+			}
+			copy(fitness[i:end], values)
+		}(i, end)
+	}
+	wait.Wait()
+	return fitness, errs
+}
+
+// Close stops the workers
+func (s *Subprocess) Close() error {
+	var errs error
+	for _, w := range s.all {
+		if w.cmd == nil {
+			continue
+		}
+		w.input.Close()
+		err := w.cmd.Wait()
+		if err != nil {
+			errs = errors.Join(errs, err)
+		}
+	}
+	s.all = nil
+	return errs
+}
+
+// Serve answers the requests read from input with the fitness of the named problems
+// until input is closed, it is the worker side of the protocol
+func Serve(input io.Reader, output io.Writer, problems func(name string) (Fitness, error)) error {
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024*1024)
+	encoder := json.NewEncoder(output)
+	for scanner.Scan() {
+		var request Request
+		err := json.Unmarshal(scanner.Bytes(), &request)
+		if err != nil {
+			return err
+		}
+		response := Response{ID: request.ID}
+		fitness, err := problems(request.Problem)
+		if err != nil {
+			response.Error = err.Error()
+		} else {
+			for _, genome := range request.Genomes {
+				response.Fitness = append(response.Fitness, Value(fitness(genome)))
+			}
+		}
+		err = encoder.Encode(response)
+		if err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,153 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestMain runs the test binary as an evaluator worker when EDA_WORKER is set
+func TestMain(m *testing.M) {
+	problems := func(name string) (Fitness, error) {
+		switch name {
+		case "sphere":
+			return sphere, nil
+		case "wall":
+			// the genomes past the wall are infeasible
+			return func(g []float32) float64 {
+				if g[0] > 0 {
+					return math.Inf(1)
+				}
+				return sphere(g)
+			}, nil
+		}
+		return nil, fmt.Errorf("unknown problem %s", name)
+	}
+	if os.Getenv("EDA_WORKER") != "" {
+		input := io.Reader(os.Stdin)
+		if os.Getenv("EDA_ONCE") != "" {
+			// answer one batch and exit
+			line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			input = strings.NewReader(line)
+		}
+		err := Serve(input, os.Stdout, problems)
+		if err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if addr := os.Getenv("EDA_COORDINATOR"); addr != "" {
+		if os.Getenv("EDA_FLAKY") != "" {
+			// read one batch and die without answering
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				os.Exit(1)
+			}
+			bufio.NewReader(conn).ReadString('\n')
+			os.Exit(0)
+		}
+		err := Work(addr, problems)
+		if err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestSubprocess(t *testing.T) {
+	evaluator, err := NewSubprocess(fmt.Sprintf("EDA_WORKER=1 '%s'", os.Args[0]), 3, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer evaluator.Close()
+
+	rng := rand.New(rand.NewSource(1))
+	optimizer := NewOptimizer(rng, 4, 4, 64, 16, nil)
+	optimizer.Name = "sphere"
+	optimizer.Evaluator = evaluator
+	err = optimizer.Run(context.Background(), 4, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, individual := range optimizer.Pop {
+		if individual.Fitness != sphere(individual.Genome) {
+			t.Fatalf("wrong fitness %f != %f", individual.Fitness, sphere(individual.Genome))
+		}
+	}
+
+	_, err = evaluator.Evaluate("rastrigin", optimizer.State)
+	if !errors.Is(err, ErrWorker) {
+		t.Fatalf("an unknown problem should fail the worker but got %v", err)
+	}
+
+	dead, err := NewSubprocess("exit 0", 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dead.Close()
+	optimizer.Evaluator = dead
+	generation := optimizer.Generation
+	err = optimizer.Run(context.Background(), 8, nil)
+	if !errors.Is(err, ErrWorker) || optimizer.Generation != generation {
+		t.Fatalf("a dead worker should stop the run but got %v", err)
+	}
+
+	once, err := NewSubprocess(fmt.Sprintf("EDA_WORKER=1 EDA_ONCE=1 '%s'", os.Args[0]), 1, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer once.Close()
+	for i, lost := range []bool{false, true, false, true, false} {
+		fitness, err := once.Evaluate("sphere", optimizer.State)
+		if errors.Is(err, ErrLost) != lost {
+			t.Fatalf("evaluation %d should be lost %t but got %v", i, lost, err)
+		}
+		if !lost && fitness[0] != sphere(optimizer.State[0]) {
+			t.Fatalf("the restarted worker returned the wrong fitness %f", fitness[0])
+		}
+	}
+}
+
+func TestInfinite(t *testing.T) {
+	evaluator, err := NewSubprocess(fmt.Sprintf("EDA_WORKER=1 '%s'", os.Args[0]), 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer evaluator.Close()
+	fitness, err := evaluator.Evaluate("wall", [][]float32{{1, 1}, {-1, 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsInf(fitness[0], 1) || fitness[1] != 2 {
+		t.Fatalf("the worker should return +Inf past the wall but returned %v", fitness)
+	}
+
+	for _, value := range []float64{math.Inf(1), math.Inf(-1), math.NaN(), 1.5} {
+		data, err := json.Marshal(Value(value))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded Value
+		err = json.Unmarshal(data, &decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Float64bits(float64(decoded)) != math.Float64bits(value) && !(math.IsNaN(value) && math.IsNaN(float64(decoded))) {
+			t.Fatalf("%f was decoded as %f from %s", value, float64(decoded), data)
+		}
+	}
+}
@@ -2,12 +2,13 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package main
+package eda
 
 import (
 	"fmt"
 	"math"
 	"math/rand"
+	"path/filepath"
 	"strings"
 
 	"github.com/pointlander/gradient/tf32"
@@ -17,11 +18,41 @@ import (
 	"gonum.org/v1/plot/plotter"
 	"gonum.org/v1/plot/vg"
 	"gonum.org/v1/plot/vg/draw"
+
+	"github.com/pointlander/entity/matrix"
+)
+
+const (
+	// B1 exponential decay of the rate for the first moment estimates
+	B1 = 0.8
+	// B2 exponential decay rate for the second-moment estimates
+	B2 = 0.89
+	// Eta is the learning rate
+	Eta = 1.0e-3
 )
 
+const (
+	// StateM is the state for the mean
+	StateM = iota
+	// StateV is the state for the variance
+	StateV
+	// StateTotal is the total number of states
+	StateTotal
+)
+
+// Log enables printing of the fitted models
+var Log bool
+
+// Deterministic fits the models with a cholesky decomposition instead of
+// gradient descent, which updates the weights from several goroutines
+var Deterministic bool
+
+// Output is the directory the plots of the fits are written to
+var Output = "."
+
 // NewMultiVariateGaussian
-func NewMultiVariateGaussian[T Float](cutoff, eta float64, graph, invert bool, rng *rand.Rand, name string, size int, vectors [][]T) (A, AI Matrix[T], u Matrix[T]) {
-	if log {
+func NewMultiVariateGaussian[T matrix.Float](cutoff, eta float64, graph, invert bool, rng *rand.Rand, name string, size int, vectors [][]T) (A, AI matrix.Matrix[T], u matrix.Matrix[T]) {
+	if Log {
 		fmt.Println(name)
 	}
 	avg := make([]T, size)
@@ -44,7 +75,7 @@ func NewMultiVariateGaussian[T Float](cutoff, eta float64, graph, invert bool, r
 			for ii, vv := range measures {
 				diff1 := avg[i] - v
 				diff2 := avg[ii] - vv
-				cov[i][ii] += diff1 * diff2
+				cov[i][ii] += T(diff1 * diff2)
 			}
 		}
 	}
@@ -55,7 +86,7 @@ func NewMultiVariateGaussian[T Float](cutoff, eta float64, graph, invert bool, r
 			}
 		}
 	}
-	if log {
+	if Log {
 		fmt.Println("K=")
 		for i := range cov {
 			fmt.Println(cov[i])
@@ -64,9 +95,20 @@ func NewMultiVariateGaussian[T Float](cutoff, eta float64, graph, invert bool, r
 		fmt.Println(avg)
 		fmt.Println()
 	}
+	return NewGaussian(cutoff, eta, graph, invert, rng, name, size, avg, cov)
+}
+
+// NewGaussian fits A with A*A^T = cov for the mean avg and covariance cov
+func NewGaussian[T matrix.Float](cutoff, eta float64, graph, invert bool, rng *rand.Rand, name string, size int, avg []T, cov [][]T) (A, AI matrix.Matrix[T], u matrix.Matrix[T]) {
+	if Deterministic {
+		return Cholesky(invert, size, avg, cov)
+	}
 
-	switch any(vectors).(type) {
-	case [][]float64:
+	switch any(avg).(type) {
+	case []float64:
+		// each fit has its own context, the node counter of the static context would be shared by the goroutines
+		fit := tf64.Context{}
+		sum, quadratic, mul := fit.U(fit.Sum), fit.B(fit.Quadratic), fit.B(fit.Mul)
 		set := tf64.NewSet()
 		set.Add("A", size, size)
 		set.Add("AI", size, size)
@@ -112,7 +154,7 @@ func NewMultiVariateGaussian[T Float](cutoff, eta float64, graph, invert bool, r
 		}
 
 		{
-			loss := tf64.Sum(tf64.Quadratic(others.Get("E"), tf64.Mul(set.Get("A"), set.Get("A"))))
+			loss := sum(quadratic(others.Get("E"), mul(set.Get("A"), set.Get("A"))))
 
 			points, i := make(plotter.XYs, 0, 8), 0
 			for {
@@ -135,7 +177,7 @@ func NewMultiVariateGaussian[T Float](cutoff, eta float64, graph, invert bool, r
 				norm := 0.0
 				for _, p := range set.Weights {
 					for _, d := range p.D {
-						norm += d * d
+						norm += float64(d * d)
 					}
 				}
 				norm = math.Sqrt(norm)
@@ -150,8 +192,8 @@ func NewMultiVariateGaussian[T Float](cutoff, eta float64, graph, invert bool, r
 					}
 					for ii, d := range w.D {
 						g := d * scaling
-						m := B1*w.States[StateM][ii] + (1-B1)*g
-						v := B2*w.States[StateV][ii] + (1-B2)*g*g
+						m := float64(B1*w.States[StateM][ii]) + float64((1-B1)*g)
+						v := float64(B2*w.States[StateV][ii]) + float64((1-B2)*g*g)
 						w.States[StateM][ii] = m
 						w.States[StateV][ii] = v
 						mhat := m / (1 - b1)
@@ -184,7 +226,7 @@ func NewMultiVariateGaussian[T Float](cutoff, eta float64, graph, invert bool, r
 				scatter.GlyphStyle.Shape = draw.CircleGlyph{}
 				p.Add(scatter)
 
-				err = p.Save(8*vg.Inch, 8*vg.Inch, fmt.Sprintf("epochs_%s.png", name))
+				err = p.Save(8*vg.Inch, 8*vg.Inch, filepath.Join(Output, fmt.Sprintf("epochs_%s.png", name)))
 				if err != nil {
 					panic(err)
 				}
@@ -192,7 +234,7 @@ func NewMultiVariateGaussian[T Float](cutoff, eta float64, graph, invert bool, r
 		}
 
 		if invert {
-			loss := tf64.Sum(tf64.Quadratic(others.Get("I"), tf64.Mul(set.Get("A"), set.Get("AI"))))
+			loss := sum(quadratic(others.Get("I"), mul(set.Get("A"), set.Get("AI"))))
 
 			points, i := make(plotter.XYs, 0, 8), 0
 			for {
@@ -215,7 +257,7 @@ func NewMultiVariateGaussian[T Float](cutoff, eta float64, graph, invert bool, r
 				norm := 0.0
 				for _, p := range set.Weights {
 					for _, d := range p.D {
-						norm += d * d
+						norm += float64(d * d)
 					}
 				}
 				norm = math.Sqrt(norm)
@@ -230,8 +272,8 @@ func NewMultiVariateGaussian[T Float](cutoff, eta float64, graph, invert bool, r
 					}
 					for ii, d := range w.D {
 						g := d * scaling
-						m := B1*w.States[StateM][ii] + (1-B1)*g
-						v := B2*w.States[StateV][ii] + (1-B2)*g*g
+						m := float64(B1*w.States[StateM][ii]) + float64((1-B1)*g)
+						v := float64(B2*w.States[StateV][ii]) + float64((1-B2)*g*g)
 						w.States[StateM][ii] = m
 						w.States[StateV][ii] = v
 						mhat := m / (1 - b1)
@@ -263,26 +305,29 @@ func NewMultiVariateGaussian[T Float](cutoff, eta float64, graph, invert bool, r
 				scatter.GlyphStyle.Shape = draw.CircleGlyph{}
 				p.Add(scatter)
 
-				err = p.Save(8*vg.Inch, 8*vg.Inch, fmt.Sprintf("inverse_epochs_%s.png", name))
+				err = p.Save(8*vg.Inch, 8*vg.Inch, filepath.Join(Output, fmt.Sprintf("inverse_epochs_%s.png", name)))
 				if err != nil {
 					panic(err)
 				}
 			}
 		}
 
-		A = NewMatrix[T](size, size)
+		A = matrix.NewMatrix[T](size, size)
 		for _, variance := range set.ByName["A"].X {
 			A.Data = append(A.Data, T(variance))
 		}
-		AI = NewMatrix[T](size, size)
+		AI = matrix.NewMatrix[T](size, size)
 		for _, variance := range set.ByName["AI"].X {
 			AI.Data = append(AI.Data, T(variance))
 		}
-		u = NewMatrix[T](size, 1)
+		u = matrix.NewMatrix[T](size, 1)
 		for _, a := range avg {
 			u.Data = append(u.Data, T(a))
 		}
-	case [][]float32:
+	case []float32:
+		// each fit has its own context, the node counter of the static context would be shared by the goroutines
+		fit := tf32.Context{}
+		sum, quadratic, mul := fit.U(fit.Sum), fit.B(fit.Quadratic), fit.B(fit.Mul)
 		set := tf32.NewSet()
 		set.Add("A", size, size)
 		set.Add("AI", size, size)
@@ -328,7 +373,7 @@ func NewMultiVariateGaussian[T Float](cutoff, eta float64, graph, invert bool, r
 		}
 
 		{
-			loss := tf32.Sum(tf32.Quadratic(others.Get("E"), tf32.Mul(set.Get("A"), set.Get("A"))))
+			loss := sum(quadratic(others.Get("E"), mul(set.Get("A"), set.Get("A"))))
 
 			points, i := make(plotter.XYs, 0, 8), 0
 			for {
@@ -366,8 +411,8 @@ func NewMultiVariateGaussian[T Float](cutoff, eta float64, graph, invert bool, r
 					}
 					for ii, d := range w.D {
 						g := d * float32(scaling)
-						m := B1*w.States[StateM][ii] + (1-B1)*g
-						v := B2*w.States[StateV][ii] + (1-B2)*g*g
+						m := float32(B1*w.States[StateM][ii]) + float32((1-B1)*g)
+						v := float32(B2*w.States[StateV][ii]) + float32((1-B2)*g*g)
 						w.States[StateM][ii] = m
 						w.States[StateV][ii] = v
 						mhat := m / (1 - float32(b1))
@@ -400,7 +445,7 @@ func NewMultiVariateGaussian[T Float](cutoff, eta float64, graph, invert bool, r
 				scatter.GlyphStyle.Shape = draw.CircleGlyph{}
 				p.Add(scatter)
 
-				err = p.Save(8*vg.Inch, 8*vg.Inch, fmt.Sprintf("epochs_%s.png", name))
+				err = p.Save(8*vg.Inch, 8*vg.Inch, filepath.Join(Output, fmt.Sprintf("epochs_%s.png", name)))
 				if err != nil {
 					panic(err)
 				}
@@ -408,7 +453,7 @@ func NewMultiVariateGaussian[T Float](cutoff, eta float64, graph, invert bool, r
 		}
 
 		if invert {
-			loss := tf32.Sum(tf32.Quadratic(others.Get("I"), tf32.Mul(set.Get("A"), set.Get("AI"))))
+			loss := sum(quadratic(others.Get("I"), mul(set.Get("A"), set.Get("AI"))))
 
 			points, i := make(plotter.XYs, 0, 8), 0
 			for {
@@ -446,8 +491,8 @@ func NewMultiVariateGaussian[T Float](cutoff, eta float64, graph, invert bool, r
 					}
 					for ii, d := range w.D {
 						g := d * float32(scaling)
-						m := B1*w.States[StateM][ii] + (1-B1)*g
-						v := B2*w.States[StateV][ii] + (1-B2)*g*g
+						m := float32(B1*w.States[StateM][ii]) + float32((1-B1)*g)
+						v := float32(B2*w.States[StateV][ii]) + float32((1-B2)*g*g)
 						w.States[StateM][ii] = m
 						w.States[StateV][ii] = v
 						mhat := m / (1 - float32(b1))
@@ -479,22 +524,22 @@ func NewMultiVariateGaussian[T Float](cutoff, eta float64, graph, invert bool, r
 				scatter.GlyphStyle.Shape = draw.CircleGlyph{}
 				p.Add(scatter)
 
-				err = p.Save(8*vg.Inch, 8*vg.Inch, fmt.Sprintf("inverse_epochs_%s.png", name))
+				err = p.Save(8*vg.Inch, 8*vg.Inch, filepath.Join(Output, fmt.Sprintf("inverse_epochs_%s.png", name)))
 				if err != nil {
 					panic(err)
 				}
 			}
 		}
 
-		A = NewMatrix[T](size, size)
+		A = matrix.NewMatrix[T](size, size)
 		for _, variance := range set.ByName["A"].X {
 			A.Data = append(A.Data, T(variance))
 		}
-		AI = NewMatrix[T](size, size)
+		AI = matrix.NewMatrix[T](size, size)
 		for _, variance := range set.ByName["AI"].X {
 			AI.Data = append(AI.Data, T(variance))
 		}
-		u = NewMatrix[T](size, 1)
+		u = matrix.NewMatrix[T](size, 1)
 		for _, a := range avg {
 			u.Data = append(u.Data, T(a))
 		}
@@ -0,0 +1,193 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+const (
+	// TopologyRing migrates from each island to the next
+	TopologyRing = iota
+	// TopologyFull migrates from each island to every other island
+	TopologyFull
+)
+
+// Islands evolves independent populations in parallel and periodically migrates
+// the best individuals between them
+type Islands struct {
+	// Islands are the optimizers of the islands
+	Islands []*Optimizer
+	// Topology is the migration topology
+	Topology int
+	// Interval is the number of generations between migrations, 0 disables migration
+	Interval int
+	// Migrants is the number of individuals each island receives in a migration
+	Migrants int
+	// MaxEvals is the maximum number of fitness evaluations of all the islands, 0 is unlimited
+	MaxEvals int
+	// Migrations is the number of migrations
+	Migrations int
+}
+
+// NewIslands creates n islands, the first is o and the others copy the settings of o
+// with their own random stream and elites. sampler creates the search distribution of
+// each copy, the default is Partitioned.
+func NewIslands(o *Optimizer, n int, sampler func() Sampler) *Islands {
+	if o.Problem == "" {
+		o.Problem = o.Name
+	}
+	name := o.Name
+	o.Name = fmt.Sprintf("%s_0", name)
+	islands := &Islands{
+		Islands:  []*Optimizer{o},
+		Migrants: 1,
+	}
+	for i := 1; i < n; i++ {
+		island := *o
+		island.Name = fmt.Sprintf("%s_%d", name, i)
+		island.Source = NewSource(o.Rng.Int63())
+		island.Rng = rand.New(island.Source)
+		island.Sampler = nil
+		if sampler != nil {
+			island.Sampler = sampler()
+		}
+		island.Champion = Individual{}
+		island.Screening.genomes, island.Screening.fitness = nil, nil
+		island.reset()
+		islands.Islands = append(islands.Islands, &island)
+	}
+	return islands
+}
+
+// Generation is the number of steps taken
+func (is *Islands) Generation() int {
+	return is.Islands[0].Generation
+}
+
+// Evals is the number of fitness evaluations of all the islands
+func (is *Islands) Evals() int {
+	evals := 0
+	for _, island := range is.Islands {
+		evals += island.Evals
+	}
+	return evals
+}
+
+// Fittest is the island with the best champion
+func (is *Islands) Fittest() *Optimizer {
+	fittest := is.Islands[0]
+	for _, island := range is.Islands[1:] {
+		if island.Champion.Fitness < fittest.Champion.Fitness {
+			fittest = island
+		}
+	}
+	return fittest
+}
+
+// Best returns the best individual found on any island
+func (is *Islands) Best() Individual {
+	return is.Fittest().Best()
+}
+
+// Step steps each island in its own goroutine and migrates every Interval generations
+func (is *Islands) Step() error {
+	done := make(chan error, len(is.Islands))
+	for _, island := range is.Islands {
+		go func(island *Optimizer) {
+			done <- island.Step()
+		}(island)
+	}
+	var errs error
+	for range is.Islands {
+		errs = errors.Join(errs, <-done)
+	}
+	if errs != nil {
+		return errs
+	}
+	if is.Interval > 0 && is.Generation()%is.Interval == 0 {
+		is.migrate()
+	}
+	return nil
+}
+
+// migrate replaces the worst elites of each island with the best individuals of its neighbors
+func (is *Islands) migrate() {
+	n := len(is.Islands)
+	if n < 2 || is.Migrants < 1 {
+		return
+	}
+	// the emigrants are copied before any island is changed
+	emigrants := make([][]Individual, n)
+	for i, island := range is.Islands {
+		migrants := min(is.Migrants, island.Cut)
+		for _, individual := range island.Pop[:migrants] {
+			emigrants[i] = append(emigrants[i], Individual{
+				Genome:     append([]float32{}, individual.Genome...),
+				Fitness:    individual.Fitness,
+				Objectives: append([]float64{}, individual.Objectives...),
+			})
+		}
+	}
+	for i, island := range is.Islands {
+		if island.Age == 0 {
+			// an island that just restarted keeps its random elites
+			continue
+		}
+		immigrants := []Individual{}
+		switch is.Topology {
+		case TopologyRing:
+			immigrants = append(immigrants, emigrants[(i+n-1)%n]...)
+		case TopologyFull:
+			for ii := range emigrants {
+				if ii != i {
+					immigrants = append(immigrants, emigrants[ii]...)
+				}
+			}
+		}
+		sort.SliceStable(immigrants, func(i, j int) bool {
+			return immigrants[i].Fitness < immigrants[j].Fitness
+		})
+		immigrants = immigrants[:min(is.Migrants, island.Cut, len(immigrants))]
+		// the elites are the first Cut individuals, the worst of them are replaced
+		for ii, immigrant := range immigrants {
+			island.Pop[island.Cut-1-ii] = immigrant
+		}
+		sortFitness(island.Pop[:island.Cut])
+		for ii := range island.State {
+			copy(island.State[ii], island.Pop[ii].Genome)
+		}
+	}
+	is.Migrations++
+}
+
+// Run steps the islands for the given number of iterations, calling callback after
+// each step until it returns true. Run stops between generations when the context is
+// done or the evaluation budget is exhausted.
+func (is *Islands) Run(ctx context.Context, iterations int, callback func(is *Islands) bool) error {
+	for is.Generation() < iterations {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		cost := 0
+		for _, island := range is.Islands {
+			cost += island.Cost()
+		}
+		if is.MaxEvals > 0 && is.Evals()+cost > is.MaxEvals {
+			return ErrBudget
+		}
+		if err := is.Step(); err != nil {
+			return err
+		}
+		if callback != nil && callback(is) {
+			return nil
+		}
+	}
+	return nil
+}
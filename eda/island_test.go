@@ -0,0 +1,54 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+)
+
+func TestIslands(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	optimizer := NewOptimizer(rng, 8, 8, 64, 16, sphere)
+	optimizer.Name = "sphere"
+	islands := NewIslands(optimizer, 4, nil)
+	islands.Interval = 2
+	islands.Migrants = 2
+	islands.Run(context.Background(), 4, nil)
+	if islands.Migrations != 2 {
+		t.Fatalf("there should be 2 migrations but there are %d", islands.Migrations)
+	}
+	for i, island := range islands.Islands {
+		if island.Generation != 4 || island.Problem != "sphere" {
+			t.Fatalf("island %d was not stepped as a sphere", i)
+		}
+	}
+
+	// the best elites of each island are in the elites of the next island
+	islands.Topology = TopologyRing
+	islands.Interval = 0
+	islands.Run(context.Background(), 5, nil)
+	n, emigrants := len(islands.Islands), []Individual{}
+	for _, island := range islands.Islands {
+		emigrants = append(emigrants, island.Pop[0])
+	}
+	islands.migrate()
+	for i, island := range islands.Islands {
+		best := emigrants[(i+n-1)%n]
+		found := false
+		for _, elite := range island.State {
+			if Euclidean(elite, best.Genome) == 0 {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("the best of island %d did not migrate", (i+n-1)%n)
+		}
+	}
+	if best := islands.Best(); best.Fitness > 1 {
+		t.Fatalf("the islands should approach the optimum but the best is %f", best.Fitness)
+	}
+}
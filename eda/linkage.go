@@ -0,0 +1,90 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/pointlander/entity/vector"
+)
+
+// Linkage assigns the genome positions to models groups so that positions
+// with strongly correlated elites share a group. For gaussian variables the
+// mutual information is -log(1-r^2)/2, so ranking by |r| is the same as
+// ranking by mutual information. The cost is O(width^2 * len(state)).
+func Linkage(rng *rand.Rand, state [][]float32, models int) []int {
+	width, n := len(state[0]), len(state)
+	columns := make([][]float32, width)
+	for i := range columns {
+		column, mean := make([]float32, n), 0.0
+		for ii := range state {
+			column[ii] = state[ii][i]
+			mean += float64(column[ii])
+		}
+		mean /= float64(n)
+		variance := 0.0
+		for _, value := range column {
+			diff := float64(value) - mean
+			variance += float64(diff * diff)
+		}
+		std := math.Sqrt(variance / float64(n))
+		for ii, value := range column {
+			if std == 0 {
+				column[ii] = 0
+				continue
+			}
+			column[ii] = float32((float64(value) - mean) / std)
+		}
+		columns[i] = column
+	}
+
+	translate := make([]int, width)
+	for i := range translate {
+		translate[i] = -1
+	}
+	order := rng.Perm(width)
+	score := make([]float64, width)
+	add := func(group, position int) {
+		translate[position] = group
+		column := columns[position]
+		for i, t := range translate {
+			if t == -1 {
+				score[i] += math.Abs(float64(vector.Dot(column, columns[i])))
+			}
+		}
+	}
+	next := 0
+	for group := range models {
+		for next < width && translate[order[next]] != -1 {
+			next++
+		}
+		if next == width {
+			break
+		}
+		for i := range score {
+			score[i] = 0
+		}
+		add(group, order[next])
+		// translate[i] = i % models gives each group these many positions
+		size := width / models
+		if group < width%models {
+			size++
+		}
+		for range size - 1 {
+			max, index := -1.0, -1
+			for _, i := range order {
+				if translate[i] == -1 && score[i] > max {
+					max, index = score[i], i
+				}
+			}
+			if index == -1 {
+				break
+			}
+			add(group, index)
+		}
+	}
+	return translate
+}
@@ -0,0 +1,26 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestLinkage(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	state := make([][]float32, 64)
+	for i := range state {
+		a, b := float32(rng.NormFloat64()), float32(rng.NormFloat64())
+		// positions 0, 2, 4 follow a and 1, 3, 5 follow b
+		state[i] = []float32{a, b, 2 * a, -b, a + .01*float32(rng.NormFloat64()), 3 * b}
+	}
+	translate := Linkage(rng, state, 2)
+	for i := 2; i < len(translate); i++ {
+		if translate[i] != translate[i%2] {
+			t.Fatalf("position %d should be linked with %d: %v", i, i%2, translate)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Metrics is the record of one generation
+type Metrics struct {
+	// Name is the name of the optimizer
+	Name string `json:"name"`
+	// Generation is the generation number
+	Generation int `json:"generation"`
+	// Best is the best fitness of the population
+	Best float64 `json:"best"`
+	// Median is the median fitness of the population
+	Median float64 `json:"median"`
+	// Worst is the worst fitness of the population
+	Worst float64 `json:"worst"`
+	// Diversity is the mean distance of the elites to their centroid
+	Diversity float64 `json:"diversity"`
+	// Fit is the time spent fitting the search distribution
+	Fit time.Duration `json:"fit_ns"`
+	// Evaluate is the time spent sampling and evaluating the offspring
+	Evaluate time.Duration `json:"evaluate_ns"`
+	// Evals is the number of fitness evaluations so far
+	Evals int `json:"evals"`
+	// Surrogate is the rank correlation of the predicted and the real fitness of the screened offspring
+	Surrogate float64 `json:"surrogate"`
+	// Operators are the offspring of each operator
+	Operators []OperatorMetrics `json:"operators"`
+}
+
+// Measure computes the best, median and worst of the fitnesses
+func (m *Metrics) Measure(fitness []float64) {
+	if len(fitness) == 0 {
+		return
+	}
+	sorted := append([]float64{}, fitness...)
+	sort.Float64s(sorted)
+	m.Best, m.Median, m.Worst = sorted[0], sorted[len(sorted)/2], sorted[len(sorted)-1]
+}
+
+// measure records the metrics of the last step
+func (o *Optimizer) measure(fit, evaluate time.Duration) {
+	fitness := make([]float64, 0, len(o.Pop))
+	for _, individual := range o.Pop {
+		// the offspring discarded by screening are not evaluated
+		if math.IsInf(individual.Fitness, 1) {
+			continue
+		}
+		fitness = append(fitness, individual.Fitness)
+	}
+	o.Metrics = Metrics{
+		Name:       o.Name,
+		Generation: o.Generation,
+		Diversity:  o.Diagnostics.Diversity,
+		Fit:        fit,
+		Evaluate:   evaluate,
+		Evals:      o.Evals,
+	}
+	o.Metrics.Measure(fitness)
+}
+
+// MetricsWriter writes metrics records as jsonl or csv
+type MetricsWriter struct {
+	format string
+	output io.Writer
+	csv    *csv.Writer
+}
+
+// NewMetricsWriter creates a new metrics writer for the jsonl or csv format
+func NewMetricsWriter(output io.Writer, format string) (*MetricsWriter, error) {
+	w := &MetricsWriter{
+		format: format,
+		output: output,
+	}
+	switch format {
+	case "jsonl":
+	case "csv":
+		w.csv = csv.NewWriter(output)
+		header := []string{"name", "generation", "best", "median", "worst",
+			"diversity", "fit_ns", "evaluate_ns", "evals", "surrogate"}
+		for _, name := range OperatorNames {
+			header = append(header, name+"_offspring", name+"_elites", name+"_improvements")
+		}
+		err := w.csv.Write(header)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown metrics format %s", format)
+	}
+	return w, nil
+}
+
+// Write writes a metrics record
+func (w *MetricsWriter) Write(m Metrics) error {
+	if w.csv == nil {
+		data, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		_, err = w.output.Write(append(data, '\n'))
+		return err
+	}
+	float := func(value float64) string {
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+	record := []string{
+		m.Name,
+		strconv.Itoa(m.Generation),
+		float(m.Best),
+		float(m.Median),
+		float(m.Worst),
+		float(m.Diversity),
+		strconv.FormatInt(int64(m.Fit), 10),
+		strconv.FormatInt(int64(m.Evaluate), 10),
+		strconv.Itoa(m.Evals),
+		float(m.Surrogate),
+	}
+	operators := make([]OperatorMetrics, OperatorTotal)
+	copy(operators, m.Operators)
+	for _, op := range operators {
+		record = append(record, strconv.Itoa(op.Offspring), strconv.Itoa(op.Elites), strconv.Itoa(op.Improvements))
+	}
+	err := w.csv.Write(record)
+	if err != nil {
+		return err
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
@@ -0,0 +1,55 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	optimizer := NewOptimizer(rng, 8, 8, 64, 16, sphere)
+	jsonl, csv := bytes.Buffer{}, bytes.Buffer{}
+	jsonlWriter, err := NewMetricsWriter(&jsonl, "jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	csvWriter, err := NewMetricsWriter(&csv, "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	optimizer.Run(context.Background(), 4, func(o *Optimizer) bool {
+		m := o.Metrics
+		if m.Generation != o.Generation || m.Evals != o.Evals {
+			t.Fatalf("wrong counters %d %d", m.Generation, m.Evals)
+		}
+		if m.Best > m.Median || m.Median > m.Worst || m.Best != o.Pop[0].Fitness {
+			t.Fatalf("wrong fitness %f %f %f", m.Best, m.Median, m.Worst)
+		}
+		if m.Fit <= 0 || m.Evaluate <= 0 || m.Diversity <= 0 {
+			t.Fatalf("missing timings or diversity %v %v %f", m.Fit, m.Evaluate, m.Diversity)
+		}
+		if err := jsonlWriter.Write(m); err != nil {
+			t.Fatal(err)
+		}
+		if err := csvWriter.Write(m); err != nil {
+			t.Fatal(err)
+		}
+		return false
+	})
+	if lines := strings.Count(jsonl.String(), "\n"); lines != 4 {
+		t.Fatalf("the jsonl should have 4 lines but has %d", lines)
+	}
+	if lines := strings.Count(csv.String(), "\n"); lines != 5 {
+		t.Fatalf("the csv should have 5 lines but has %d", lines)
+	}
+	if _, err := NewMetricsWriter(&csv, "xml"); err == nil {
+		t.Fatal("xml should be an unknown format")
+	}
+}
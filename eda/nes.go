@@ -0,0 +1,209 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"bytes"
+	"encoding/gob"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// NES is a natural evolution strategy that moves a mean genome along a search gradient
+// estimated from mirrored gaussian perturbations, no covariance is fit
+type NES struct {
+	// Sigma is the standard deviation of the perturbations
+	Sigma float64
+	// Eta is the learning rate of the mean
+	Eta float64
+	// Mean is the mean genome
+	Mean []float64
+	// Noise are the perturbations of the current generation, one per mirrored pair
+	Noise [][]float32
+	// M is the first moment estimate of the gradient
+	M []float64
+	// V is the second moment estimate of the gradient
+	V []float64
+
+	samples [][]float32
+	steps   int
+}
+
+// nesState is the state of NES in a checkpoint
+type nesState struct {
+	Mean  []float64
+	Noise [][]float32
+	M, V  []float64
+	Steps int
+	// Samples are the indexes of the samples of the last generation in the population
+	Samples []int
+}
+
+// NewNES creates a new natural evolution strategy sampler
+func NewNES() *NES {
+	return &NES{
+		Sigma: .1,
+		Eta:   .05,
+	}
+}
+
+// Fit estimates the search gradient from the last generation and updates the mean with adam
+func (n *NES) Fit(o *Optimizer) {
+	width := o.Width
+	if o.Age == 0 || len(n.Mean) != width {
+		n.Mean = make([]float64, width)
+		for _, elite := range o.State {
+			for i, value := range elite {
+				n.Mean[i] += float64(value) / float64(len(o.State))
+			}
+		}
+		n.M, n.V = make([]float64, width), make([]float64, width)
+		n.steps = 0
+	} else {
+		n.step(o)
+	}
+
+	born := o.Born()
+	n.Noise = make([][]float32, (born+1)/2)
+	for i := range n.Noise {
+		n.Noise[i] = make([]float32, width)
+		for ii := range n.Noise[i] {
+			n.Noise[i][ii] = float32(o.Rng.NormFloat64())
+		}
+	}
+	n.samples = make([][]float32, born)
+}
+
+// step takes one adam step along the search gradient
+func (n *NES) step(o *Optimizer) {
+	index := make(map[*float32]int, len(n.samples))
+	for i, sample := range n.samples {
+		if len(sample) > 0 {
+			index[&sample[0]] = i
+		}
+	}
+	type Sample struct {
+		Index   int
+		Fitness float64
+	}
+	samples := make([]Sample, 0, len(n.samples))
+	for _, individual := range o.Pop {
+		// the offspring discarded by screening have no fitness to rank
+		if len(individual.Genome) == 0 || individual.Screened {
+			continue
+		}
+		if i, ok := index[&individual.Genome[0]]; ok {
+			samples = append(samples, Sample{Index: i, Fitness: individual.Fitness})
+		}
+	}
+	if len(samples) < 2 {
+		return
+	}
+	sort.SliceStable(samples, func(i, j int) bool {
+		return samples[i].Fitness < samples[j].Fitness
+	})
+
+	// centered ranks, the best sample has the lowest rank
+	gradient := make([]float64, len(n.Mean))
+	for rank, sample := range samples {
+		utility := float64(rank)/float64(len(samples)-1) - .5
+		noise, sign := n.Noise[sample.Index/2], 1.0
+		if sample.Index%2 == 1 {
+			sign = -1
+		}
+		for i, value := range noise {
+			gradient[i] += float64(sign * utility * float64(value))
+		}
+	}
+	scale := 1 / (float64(len(samples)) * n.Sigma)
+	n.steps++
+	pow := func(x float64) float64 {
+		return math.Pow(x, float64(n.steps))
+	}
+	b1, b2 := pow(B1), pow(B2)
+	for i, g := range gradient {
+		g *= scale
+		m := float64(B1*n.M[i]) + float64((1-B1)*g)
+		v := float64(B2*n.V[i]) + float64((1-B2)*g*g)
+		n.M[i], n.V[i] = m, v
+		mhat := m / (1 - b1)
+		vhat := v / (1 - b2)
+		n.Mean[i] -= float64(n.Eta * mhat / (math.Sqrt(vhat) + 1e-8))
+	}
+}
+
+// State encodes the mean, the adam moments and the samples of the last generation
+func (n *NES) State(o *Optimizer) ([]byte, error) {
+	index := make(map[*float32]int, len(o.Pop))
+	for i, individual := range o.Pop {
+		if len(individual.Genome) > 0 {
+			index[&individual.Genome[0]] = i
+		}
+	}
+	samples := make([]int, len(n.samples))
+	for i, sample := range n.samples {
+		samples[i] = -1
+		if len(sample) == 0 {
+			continue
+		}
+		if ii, ok := index[&sample[0]]; ok {
+			samples[i] = ii
+		}
+	}
+	buffer := bytes.Buffer{}
+	err := gob.NewEncoder(&buffer).Encode(nesState{
+		Mean:    n.Mean,
+		Noise:   n.Noise,
+		M:       n.M,
+		V:       n.V,
+		Steps:   n.steps,
+		Samples: samples,
+	})
+	return buffer.Bytes(), err
+}
+
+// Restore decodes the mean, the adam moments and the samples of the last generation,
+// which are linked to their individuals in the loaded population
+func (n *NES) Restore(o *Optimizer, state []byte) error {
+	s := nesState{}
+	err := gob.NewDecoder(bytes.NewReader(state)).Decode(&s)
+	if err != nil {
+		return err
+	}
+	n.Mean, n.Noise = s.Mean, s.Noise
+	n.M, n.V, n.steps = s.M, s.V, s.Steps
+	n.samples = make([][]float32, len(s.Samples))
+	for i, ii := range s.Samples {
+		if ii >= 0 && ii < len(o.Pop) {
+			n.samples[i] = o.Pop[ii].Genome
+		}
+	}
+	return nil
+}
+
+// SampleIndex samples the mirrored perturbation of the offspring
+func (n *NES) SampleIndex(rng *rand.Rand, index int, genome []float32) {
+	noise, sign := n.Noise[index/2], float32(n.Sigma)
+	if index%2 == 1 {
+		sign = -sign
+	}
+	for i, value := range noise {
+		genome[i] = float32(n.Mean[i]) + float32(sign*value)
+	}
+	n.samples[index] = genome
+}
+
+// Sample samples an unmirrored perturbation of the mean
+func (n *NES) Sample(rng *rand.Rand, genome []float32) {
+	for i := range genome {
+		genome[i] = float32(n.Mean[i] + float64(n.Sigma*rng.NormFloat64()))
+	}
+}
+
+// Trace is the trace of the isotropic covariance
+func (n *NES) Trace() float64 {
+	return float64(len(n.Mean)) * n.Sigma * n.Sigma
+}
@@ -0,0 +1,128 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+func TestNES(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	optimizer := NewOptimizer(rng, 64, 64, 64, 16, func(g []float32) float64 {
+		fitness := 0.0
+		for _, value := range g {
+			diff := float64(value) - 1
+			fitness += diff * diff
+		}
+		return fitness
+	})
+	nes := NewNES()
+	optimizer.Sampler = nes
+	optimizer.Run(context.Background(), 1, nil)
+	for i, noise := range nes.Noise {
+		for ii, value := range noise {
+			plus := nes.samples[2*i][ii] - float32(nes.Mean[ii])
+			minus := nes.samples[2*i+1][ii] - float32(nes.Mean[ii])
+			if math.Abs(float64(plus+minus)) > 1e-5 || math.Abs(float64(plus)-nes.Sigma*float64(value)) > 1e-5 {
+				t.Fatalf("the samples %d are not mirrored", i)
+			}
+		}
+	}
+	optimizer.Run(context.Background(), 256, nil)
+	distance := 0.0
+	for _, value := range nes.Mean {
+		distance += (value - 1) * (value - 1)
+	}
+	if distance > .5 {
+		t.Fatalf("the mean should approach the optimum but is %f away", distance)
+	}
+}
+
+func TestNESResume(t *testing.T) {
+	ones := func(g []float32) float64 {
+		fitness := 0.0
+		for _, value := range g {
+			diff := float64(value) - 1
+			fitness += diff * diff
+		}
+		return fitness
+	}
+	path := filepath.Join(t.TempDir(), "nes.checkpoint")
+	source := NewSource(1)
+	optimizer := NewOptimizer(rand.New(source), 16, 16, 32, 8, ones)
+	optimizer.Source = source
+	optimizer.Sampler = NewNES()
+	optimizer.Run(context.Background(), 4, nil)
+	err := optimizer.Save(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	optimizer.Run(context.Background(), 8, nil)
+
+	source = NewSource(1)
+	resumed := NewOptimizer(rand.New(source), 16, 16, 32, 8, ones)
+	resumed.Source = source
+	nes := NewNES()
+	resumed.Sampler = nes
+	err = resumed.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nes.steps != 3 {
+		t.Fatalf("the mean should have taken 3 steps but took %d", nes.steps)
+	}
+	resumed.Run(context.Background(), 8, nil)
+	for i, value := range optimizer.Sampler.(*NES).Mean {
+		if value != nes.Mean[i] {
+			t.Fatalf("resumed mean differs at %d: %f != %f", i, value, nes.Mean[i])
+		}
+	}
+	for i := range optimizer.Pop {
+		if optimizer.Pop[i].Fitness != resumed.Pop[i].Fitness {
+			t.Fatalf("resumed run differs at %d: %f != %f", i, optimizer.Pop[i].Fitness, resumed.Pop[i].Fitness)
+		}
+	}
+}
+
+func TestNESScreened(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	optimizer := NewOptimizer(rng, 8, 8, 32, 8, sphere)
+	nes := NewNES()
+	optimizer.Sampler = nes
+	optimizer.Screening = Screening{Surrogate: KNN{K: 4}, Fraction: .25, Memory: 64}
+	optimizer.Run(context.Background(), 4, nil)
+	// the step is the same without the discarded offspring
+	evaluated := *optimizer
+	evaluated.Pop = nil
+	screened := 0
+	for _, individual := range optimizer.Pop {
+		if individual.Screened {
+			screened++
+			continue
+		}
+		evaluated.Pop = append(evaluated.Pop, individual)
+	}
+	if screened == 0 {
+		t.Fatal("the surrogate should discard offspring")
+	}
+	clone := func() *NES {
+		n := *nes
+		n.Mean = append([]float64{}, nes.Mean...)
+		n.M, n.V = append([]float64{}, nes.M...), append([]float64{}, nes.V...)
+		return &n
+	}
+	a, b := clone(), clone()
+	a.step(optimizer)
+	b.step(&evaluated)
+	for i := range a.Mean {
+		if a.Mean[i] != b.Mean[i] {
+			t.Fatalf("the discarded offspring moved the mean at %d: %f != %f", i, a.Mean[i], b.Mean[i])
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/pointlander/entity/vector"
+)
+
+// Network is a sparse gaussian bayesian network over the genome learned
+// from the elites by greedily adding the parents that improve the bayesian
+// information criterion, in the style of EGNA. The variables are visited in
+// a random order and may only have parents earlier in the order, so the
+// graph is always acyclic.
+type Network struct {
+	// MaxParents is the maximum number of parents of a variable
+	MaxParents int
+	// Candidates is the number of earlier variables considered as parents
+	Candidates int
+
+	// Order is the topological order of the variables
+	Order []int
+	// Parents are the parents of each variable
+	Parents [][]int
+	// Beta are the regression coefficients on the parents
+	Beta [][]float64
+	// Mean is the mean of each variable
+	Mean []float64
+	// Sigma is the residual standard deviation of each variable
+	Sigma []float64
+
+	trace float64
+}
+
+// NewNetwork creates a new gaussian bayesian network sampler
+func NewNetwork() *Network {
+	return &Network{
+		MaxParents: 3,
+		Candidates: 16,
+	}
+}
+
+// Fit learns the structure and parameters of the network from the elites
+func (n *Network) Fit(o *Optimizer) {
+	rng, width, samples := o.Rng, o.Width, len(o.State)
+	n.Mean = make([]float64, width)
+	columns := make([][]float32, width)
+	for i := range columns {
+		column, mean := make([]float32, samples), 0.0
+		for ii := range o.State {
+			mean += float64(o.State[ii][i])
+		}
+		mean /= float64(samples)
+		for ii := range o.State {
+			column[ii] = float32(float64(o.State[ii][i]) - mean)
+		}
+		n.Mean[i], columns[i] = mean, column
+	}
+	cov := func(a, b int) float64 {
+		return float64(vector.Dot(columns[a], columns[b])) / float64(samples)
+	}
+	n.trace = 0
+	for i := range columns {
+		n.trace += cov(i, i)
+	}
+
+	n.Order = rng.Perm(width)
+	n.Parents = make([][]int, width)
+	n.Beta = make([][]float64, width)
+	n.Sigma = make([]float64, width)
+	penalty := math.Log(float64(samples)) / 2
+	// fit regresses variable on parents returning the coefficients and the residual variance
+	fit := func(variable int, parents []int) ([]float64, float64) {
+		k := len(parents)
+		s := make([][]float64, k)
+		c := make([]float64, k)
+		for i, p := range parents {
+			s[i] = make([]float64, k)
+			for ii, pp := range parents {
+				s[i][ii] = cov(p, pp)
+			}
+			s[i][i] += 1e-9
+			c[i] = cov(p, variable)
+		}
+		beta := solve(s, c)
+		variance := cov(variable, variable)
+		for i := range beta {
+			variance -= float64(beta[i] * c[i])
+		}
+		if variance < 1e-12 {
+			variance = 1e-12
+		}
+		return beta, variance
+	}
+	bic := func(variance float64, parents int) float64 {
+		return float64(-float64(samples)/2*math.Log(variance)) - float64(float64(parents+2)*penalty)
+	}
+	process := func(index int, seed int64) {
+		rng := rand.New(rand.NewSource(seed))
+		variable := n.Order[index]
+		candidates := n.Order[:index]
+		if len(candidates) > n.Candidates {
+			candidates = make([]int, 0, n.Candidates)
+			for _, i := range rng.Perm(index)[:n.Candidates] {
+				candidates = append(candidates, n.Order[i])
+			}
+		}
+		parents, beta, variance := []int{}, []float64{}, cov(variable, variable)
+		if variance < 1e-12 {
+			variance = 1e-12
+		}
+		score := bic(variance, 0)
+		for len(parents) < n.MaxParents {
+			best, bestBeta, bestVariance, bestScore := -1, []float64(nil), 0.0, score
+			for _, candidate := range candidates {
+				skip := false
+				for _, p := range parents {
+					if p == candidate {
+						skip = true
+						break
+					}
+				}
+				if skip {
+					continue
+				}
+				b, v := fit(variable, append(append([]int{}, parents...), candidate))
+				if s := bic(v, len(parents)+1); s > bestScore {
+					best, bestBeta, bestVariance, bestScore = candidate, b, v, s
+				}
+			}
+			if best == -1 {
+				break
+			}
+			parents, beta, variance, score = append(parents, best), bestBeta, bestVariance, bestScore
+		}
+		n.Parents[variable], n.Beta[variable], n.Sigma[variable] = parents, beta, math.Sqrt(variance)
+	}
+
+	const chunk = 256
+	chunks := seeds(rng, (width+chunk-1)/chunk)
+	parallel(len(chunks), func(i int) {
+		rng := rand.New(rand.NewSource(chunks[i]))
+		for index := i * chunk; index < (i+1)*chunk && index < width; index++ {
+			process(index, rng.Int63())
+		}
+	})
+}
+
+// Sample samples the network ancestrally in topological order
+func (n *Network) Sample(rng *rand.Rand, genome []float32) {
+	for _, variable := range n.Order {
+		value := n.Mean[variable] + float64(n.Sigma[variable]*rng.NormFloat64())
+		for i, parent := range n.Parents[variable] {
+			value += float64(n.Beta[variable][i] * (float64(genome[parent]) - n.Mean[parent]))
+		}
+		genome[variable] = float32(value)
+	}
+}
+
+// Trace is the total variance of the elites the network was fit to
+func (n *Network) Trace() float64 {
+	return n.trace
+}
+
+// solve solves s*x = c with gaussian elimination and partial pivoting
+func solve(s [][]float64, c []float64) []float64 {
+	k := len(c)
+	a := make([][]float64, k)
+	for i := range a {
+		a[i] = append(append([]float64{}, s[i]...), c[i])
+	}
+	for i := range k {
+		pivot := i
+		for ii := i + 1; ii < k; ii++ {
+			if math.Abs(a[ii][i]) > math.Abs(a[pivot][i]) {
+				pivot = ii
+			}
+		}
+		a[i], a[pivot] = a[pivot], a[i]
+		if a[i][i] == 0 {
+			continue
+		}
+		for ii := i + 1; ii < k; ii++ {
+			factor := a[ii][i] / a[i][i]
+			for iii := i; iii <= k; iii++ {
+				a[ii][iii] -= float64(factor * a[i][iii])
+			}
+		}
+	}
+	x := make([]float64, k)
+	for i := k - 1; i >= 0; i-- {
+		if a[i][i] == 0 {
+			continue
+		}
+		sum := a[i][k]
+		for ii := i + 1; ii < k; ii++ {
+			sum -= float64(a[i][ii] * x[ii])
+		}
+		x[i] = sum / a[i][i]
+	}
+	return x
+}
@@ -0,0 +1,43 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+)
+
+func TestNetwork(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	optimizer := NewOptimizer(rng, 16, 4, 128, 16, sphere)
+	optimizer.Sampler = NewNetwork()
+	first := 0.0
+	optimizer.Run(context.Background(), 16, func(o *Optimizer) bool {
+		if o.Generation == 1 {
+			first = o.Best().Fitness
+		}
+		return false
+	})
+	if best := optimizer.Best().Fitness; best >= first {
+		t.Fatalf("fitness did not improve %f >= %f", best, first)
+	}
+
+	state := make([][]float32, 256)
+	for i := range state {
+		a := float32(rng.NormFloat64())
+		state[i] = []float32{a, 2*a + .1*float32(rng.NormFloat64()), float32(rng.NormFloat64())}
+	}
+	optimizer = NewOptimizer(rng, 3, 3, 256, 256, sphere)
+	optimizer.State = state
+	network := NewNetwork()
+	network.Fit(optimizer)
+	if len(network.Parents[0])+len(network.Parents[1]) != 1 {
+		t.Fatalf("variables 0 and 1 should be linked: %v", network.Parents)
+	}
+	if len(network.Parents[2]) != 0 {
+		t.Fatalf("variable 2 should be independent: %v", network.Parents)
+	}
+}
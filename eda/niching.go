@@ -0,0 +1,198 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+const (
+	// NichingNone selects the best individuals as elites
+	NichingNone = iota
+	// NichingClearing keeps the Capacity best individuals of each niche and clears the rest
+	NichingClearing
+	// NichingReplacement is restricted tournament replacement with all the elites as the window,
+	// each offspring replaces the nearest elite when it is better. The offspring are sampled from
+	// the model and have no parents, so this stands in for deterministic crowding
+	NichingReplacement
+	// NichingSharing ranks individuals by their fitness rank times their niche count
+	NichingSharing
+)
+
+// Distance is the distance between two genomes
+type Distance func(a, b []float32) float64
+
+// Euclidean is the euclidean distance between two genomes
+func Euclidean(a, b []float32) float64 {
+	sum := 0.0
+	for i, value := range a {
+		diff := float64(value - b[i])
+		sum += float64(diff * diff)
+	}
+	return math.Sqrt(sum)
+}
+
+// Hamming is the number of positions where the genomes have different signs,
+// the distance between the bits of genomes decoded by thresholding at zero
+func Hamming(a, b []float32) float64 {
+	sum := 0.0
+	for i, value := range a {
+		if (value > 0) != (b[i] > 0) {
+			sum++
+		}
+	}
+	return sum
+}
+
+// Niching is the diversity preservation scheme used to select the elites
+type Niching struct {
+	// Scheme is the niching scheme
+	Scheme int
+	// Radius is the niche radius
+	Radius float64
+	// Capacity is the number of winners of each niche for clearing
+	Capacity int
+	// Distance is the genome distance, the default is Euclidean
+	Distance Distance
+}
+
+func (n Niching) distance() Distance {
+	if n.Distance == nil {
+		return Euclidean
+	}
+	return n.Distance
+}
+
+// sortFitness sorts individuals by fitness
+func sortFitness(pop []Individual) {
+	sort.Slice(pop, func(i, j int) bool {
+		return pop[i].Fitness < pop[j].Fitness
+	})
+}
+
+// selectElites orders the population so the elites are the first cut individuals
+func (o *Optimizer) selectElites(born int) {
+	if o.Objectives != nil {
+		o.selectPareto()
+		return
+	}
+	n, distance := o.Niching, o.Niching.distance()
+	switch {
+	case n.Scheme == NichingClearing:
+		sortFitness(o.Pop)
+		capacity := n.Capacity
+		if capacity < 1 {
+			capacity = 1
+		}
+		winners, cleared := make([]Individual, 0, len(o.Pop)), make([]Individual, 0, len(o.Pop))
+		taken := make([]bool, len(o.Pop))
+		for i := range o.Pop {
+			if taken[i] {
+				continue
+			}
+			if len(winners) >= o.Cut {
+				// the order after the elites doesn't matter
+				cleared = append(cleared, o.Pop[i])
+				continue
+			}
+			count := 0
+			for ii := i; ii < len(o.Pop); ii++ {
+				if taken[ii] || distance(o.Pop[i].Genome, o.Pop[ii].Genome) > n.Radius {
+					continue
+				}
+				taken[ii] = true
+				if count < capacity {
+					winners = append(winners, o.Pop[ii])
+				} else {
+					cleared = append(cleared, o.Pop[ii])
+				}
+				count++
+			}
+		}
+		copy(o.Pop, append(winners, cleared...))
+	case n.Scheme == NichingReplacement && born < len(o.Pop):
+		elites := o.Pop[:len(o.Pop)-born]
+		for i := len(elites); i < len(o.Pop); i++ {
+			min, index := math.MaxFloat64, 0
+			for ii := range elites {
+				if d := distance(o.Pop[i].Genome, elites[ii].Genome); d < min {
+					min, index = d, ii
+				}
+			}
+			if o.Pop[i].Fitness < elites[index].Fitness {
+				o.Pop[i], elites[index] = elites[index], o.Pop[i]
+			}
+		}
+		sortFitness(elites)
+		sortFitness(o.Pop[len(elites):])
+	case n.Scheme == NichingSharing:
+		sortFitness(o.Pop)
+		shared := make([]float64, len(o.Pop))
+		for i := range o.Pop {
+			count := 0.0
+			for ii := range o.Pop {
+				if d := distance(o.Pop[i].Genome, o.Pop[ii].Genome); d < n.Radius {
+					count += 1 - d/n.Radius
+				}
+			}
+			shared[i] = float64(i+1) * math.Max(count, 1)
+		}
+		index := make([]int, len(o.Pop))
+		for i := range index {
+			index[i] = i
+		}
+		sort.SliceStable(index, func(i, j int) bool {
+			return shared[index[i]] < shared[index[j]]
+		})
+		pop := make([]Individual, len(o.Pop))
+		for i, ii := range index {
+			pop[i] = o.Pop[ii]
+		}
+		copy(o.Pop, pop)
+	default:
+		sortFitness(o.Pop)
+	}
+}
+
+// Archive collects the distinct individuals that reach a target fitness
+type Archive struct {
+	// Target is the fitness an individual must reach to be collected
+	Target float64
+	// Radius is the distance beyond which two optima are distinct
+	Radius float64
+	// Distance is the genome distance, the default is Euclidean
+	Distance Distance
+	// Optima are the distinct optima found
+	Optima []Individual
+
+	mutex sync.Mutex
+}
+
+// Collect adds the distinct optima in pop to the archive
+func (a *Archive) Collect(pop []Individual) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	distance := a.Distance
+	if distance == nil {
+		distance = Euclidean
+	}
+	for _, individual := range pop {
+		if individual.Genome == nil || individual.Fitness > a.Target {
+			continue
+		}
+		distinct := true
+		for _, optimum := range a.Optima {
+			if distance(individual.Genome, optimum.Genome) <= a.Radius {
+				distinct = false
+				break
+			}
+		}
+		if distinct {
+			a.Optima = append(a.Optima, individual)
+		}
+	}
+}
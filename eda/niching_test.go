@@ -0,0 +1,55 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"testing"
+)
+
+func TestNiching(t *testing.T) {
+	individual := func(fitness float64, genome ...float32) Individual {
+		return Individual{Genome: genome, Fitness: fitness}
+	}
+	optimizer := &Optimizer{
+		Cut: 2,
+		Pop: []Individual{
+			individual(3, 0, 0),
+			individual(1, 10, 10),
+			individual(0, 0, .1),
+			individual(2, 10, 10.1),
+		},
+		Niching: Niching{
+			Scheme: NichingClearing,
+			Radius: 1,
+		},
+	}
+	optimizer.selectElites(4)
+	if optimizer.Pop[0].Fitness != 0 || optimizer.Pop[1].Fitness != 1 {
+		t.Fatalf("clearing should keep the best of each niche: %v", optimizer.Pop)
+	}
+
+	optimizer.Niching.Scheme = NichingReplacement
+	optimizer.Pop = []Individual{
+		individual(1, 0, 0),
+		individual(2, 10, 10),
+		individual(0, 0, .1),
+		individual(3, 10, 10.1),
+	}
+	optimizer.selectElites(2)
+	if optimizer.Pop[0].Fitness != 0 || optimizer.Pop[1].Fitness != 2 {
+		t.Fatalf("replacement should replace the nearest elite: %v", optimizer.Pop)
+	}
+
+	archive := Archive{Target: 0, Distance: Hamming}
+	archive.Collect([]Individual{
+		individual(0, 1, -1),
+		individual(0, 2, -2),
+		individual(0, -1, 1),
+		individual(1, 1, 1),
+	})
+	if len(archive.Optima) != 2 {
+		t.Fatalf("there should be 2 distinct optima: %v", archive.Optima)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Noise is the handling of a noisy stochastic fitness
+type Noise struct {
+	// Samples is the number of fitness samples averaged for each offspring
+	Samples int
+	// Reevaluate samples the elites again each generation, averaging over all of their samples
+	Reevaluate bool
+	// Racing drops the candidates whose mean is worse than the Cut best by more than
+	// this many standard errors after each round of samples, 0 disables racing
+	Racing float64
+	// Common draws the samples of a round from the same random stream for every
+	// candidate, so they are compared on the same inputs
+	Common bool
+}
+
+// active is true when the fitness is sampled in rounds
+func (n Noise) active() bool {
+	return n.Samples > 1 || n.Reevaluate || n.Racing > 0 || n.Common
+}
+
+// add adds a fitness sample to the running mean and variance of the individual
+func (i *Individual) add(value float64) {
+	squares := i.Variance * float64(max(i.Samples-1, 0))
+	i.Samples++
+	delta := value - i.Fitness
+	i.Fitness += delta / float64(i.Samples)
+	squares += float64(delta * (value - i.Fitness))
+	if i.Samples > 1 {
+		i.Variance = squares / float64(i.Samples-1)
+	}
+}
+
+// noisy samples the stochastic fitness of the indexed offspring and of the elites
+// when they are re-evaluated in rounds, racing the candidates between the rounds.
+// It returns the number of fitness evaluations.
+func (o *Optimizer) noisy(born []Individual, index []int, rngs []*rand.Rand) int {
+	noise := o.Noise
+	candidates, streams := []*Individual{}, []*rand.Rand{}
+	if noise.Reevaluate {
+		for i := range o.Pop[:len(o.Pop)-len(born)] {
+			candidates = append(candidates, &o.Pop[i])
+			streams = append(streams, rand.New(rand.NewSource(o.Rng.Int63())))
+		}
+	}
+	for _, ii := range index {
+		born[ii].Fitness, born[ii].Samples, born[ii].Variance = 0, 0, 0
+		candidates = append(candidates, &born[ii])
+		streams = append(streams, rngs[ii])
+	}
+	seeds := make([]int64, max(noise.Samples, 1))
+	for i := range seeds {
+		seeds[i] = o.Rng.Int63()
+	}
+
+	alive := make([]int, len(candidates))
+	for i := range alive {
+		alive[i] = i
+	}
+	evals := 0
+	for round, seed := range seeds {
+		parallel(len(alive), func(i int) {
+			ii := alive[i]
+			rng := streams[ii]
+			if noise.Common {
+				rng = rand.New(rand.NewSource(seed))
+			}
+			candidates[ii].add(o.Stochastic(candidates[ii].Genome, rng))
+		})
+		evals += len(alive)
+
+		if noise.Racing > 0 && round > 0 && round < len(seeds)-1 && len(alive) > o.Cut {
+			alive = o.race(candidates, alive)
+		}
+	}
+	return evals
+}
+
+// race keeps the candidates that could still be among the Cut best
+func (o *Optimizer) race(candidates []*Individual, alive []int) []int {
+	bound := func(i int, z float64) float64 {
+		c := candidates[i]
+		return c.Fitness + float64(z*math.Sqrt(c.Variance/float64(c.Samples)))
+	}
+	upper := make([]float64, len(alive))
+	for i, ii := range alive {
+		upper[i] = bound(ii, o.Noise.Racing)
+	}
+	sort.Float64s(upper)
+	threshold := upper[o.Cut-1]
+	racing := []int{}
+	for _, ii := range alive {
+		if bound(ii, -o.Noise.Racing) <= threshold {
+			racing = append(racing, ii)
+		}
+	}
+	return racing
+}
@@ -0,0 +1,63 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+)
+
+func TestNoise(t *testing.T) {
+	noisy := func(g []float32, rng *rand.Rand) float64 {
+		return sphere(g) + rng.NormFloat64()
+	}
+	rng := rand.New(rand.NewSource(1))
+	optimizer := NewOptimizer(rng, 4, 4, 64, 16, nil)
+	optimizer.Stochastic = noisy
+	optimizer.Noise = Noise{Samples: 4, Reevaluate: true, Racing: 2}
+	optimizer.Run(context.Background(), 8, nil)
+	// the first generation has no elites and racing drops some of the offspring
+	if maximum := 64*4 + 7*64*4; optimizer.Evals >= maximum || optimizer.Evals <= 64*4 {
+		t.Fatalf("racing should drop offspring but there are %d evaluations", optimizer.Evals)
+	}
+	for _, elite := range optimizer.Pop[:optimizer.Cut] {
+		if elite.Samples < 2 {
+			t.Fatalf("the elites should be averaged over their samples but one has %d", elite.Samples)
+		}
+	}
+
+	// with common random numbers the noise is the same for every candidate
+	common := func(g []float32, rng *rand.Rand) float64 {
+		return rng.Float64()
+	}
+	optimizer = NewOptimizer(rng, 4, 4, 64, 16, nil)
+	optimizer.Stochastic = common
+	optimizer.Noise = Noise{Samples: 2, Common: true}
+	optimizer.Run(context.Background(), 1, nil)
+	for _, individual := range optimizer.Pop {
+		if individual.Fitness != optimizer.Pop[0].Fitness || individual.Samples != 2 {
+			t.Fatalf("the offspring should see the same samples %f != %f", individual.Fitness, optimizer.Pop[0].Fitness)
+		}
+	}
+}
+
+func TestNoiseBudget(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	optimizer := NewOptimizer(rng, 4, 4, 64, 16, nil)
+	optimizer.Stochastic = func(g []float32, rng *rand.Rand) float64 {
+		return sphere(g) + rng.NormFloat64()
+	}
+	optimizer.Noise = Noise{Samples: 4, Reevaluate: true}
+	// the second generation samples the offspring and the re-evaluated elites 4 times
+	optimizer.MaxEvals = 2*64*4 + 100
+	err := optimizer.Run(context.Background(), 8, nil)
+	if err != ErrBudget {
+		t.Fatalf("budget should be exhausted but got %v", err)
+	}
+	if optimizer.Evals > optimizer.MaxEvals || optimizer.Generation != 2 {
+		t.Fatalf("%d evaluations in %d generations exceed the budget of %d", optimizer.Evals, optimizer.Generation, optimizer.MaxEvals)
+	}
+}
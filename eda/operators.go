@@ -0,0 +1,134 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"math"
+	"math/rand"
+)
+
+const (
+	// OperatorSample samples the search distribution
+	OperatorSample = iota
+	// OperatorUniform is uniform crossover of two elites
+	OperatorUniform
+	// OperatorBlock is crossover of the blocks of two elites, the blocks are the
+	// partitions of the last fit or else a contiguous segment
+	OperatorBlock
+	// OperatorGaussian is gaussian mutation of an elite
+	OperatorGaussian
+	// OperatorFlip flips the signs of the genes of an elite, which flips the bits of Bits
+	OperatorFlip
+	// OperatorTotal is the number of operators
+	OperatorTotal
+)
+
+// OperatorNames are the names of the operators
+var OperatorNames = [OperatorTotal]string{"sample", "uniform", "block", "gaussian", "flip"}
+
+// Operators are the proportions of the offspring produced by the genetic operators,
+// the rest of the offspring are sampled from the search distribution
+type Operators struct {
+	// Uniform is the proportion of uniform crossover
+	Uniform float64
+	// Block is the proportion of blockwise crossover
+	Block float64
+	// Gaussian is the proportion of gaussian mutation
+	Gaussian float64
+	// Flip is the proportion of bit flip mutation
+	Flip float64
+	// Sigma is the standard deviation of gaussian mutation
+	Sigma float64
+	// Rate is the probability that a gene is mutated, 1/width when 0
+	Rate float64
+}
+
+// counts is the number of offspring produced by each operator
+func (p Operators) counts(born int) [OperatorTotal]int {
+	var counts [OperatorTotal]int
+	proportions := [OperatorTotal]float64{0, p.Uniform, p.Block, p.Gaussian, p.Flip}
+	rest := born
+	for op := OperatorUniform; op < OperatorTotal; op++ {
+		counts[op] = min(int(math.Round(proportions[op]*float64(born))), rest)
+		rest -= counts[op]
+	}
+	counts[OperatorSample] = rest
+	return counts
+}
+
+// operator is the operator of the offspring at index, the sampled offspring come first
+func (p Operators) operator(index, born int) int {
+	counts := p.counts(born)
+	for op, count := range counts {
+		if index < count {
+			return op
+		}
+		index -= count
+	}
+	return OperatorSample
+}
+
+// apply produces an offspring from the elites with a genetic operator
+func (p Operators) apply(op int, rng *rand.Rand, elites [][]float32, translate []int, genome []float32) {
+	a := elites[rng.Intn(len(elites))]
+	b := elites[rng.Intn(len(elites))]
+	rate := p.Rate
+	if rate == 0 {
+		rate = 1 / float64(len(genome))
+	}
+	copy(genome, a)
+	switch op {
+	case OperatorUniform:
+		for i := range genome {
+			if rng.Intn(2) == 0 {
+				genome[i] = b[i]
+			}
+		}
+	case OperatorBlock:
+		if len(translate) == len(genome) {
+			blocks := 0
+			for _, t := range translate {
+				blocks = max(blocks, t+1)
+			}
+			crossed := make([]bool, blocks)
+			for i := range crossed {
+				crossed[i] = rng.Intn(2) == 0
+			}
+			for i, t := range translate {
+				if crossed[t] {
+					genome[i] = b[i]
+				}
+			}
+			break
+		}
+		begin := rng.Intn(len(genome))
+		end := begin + 1 + rng.Intn(len(genome)-begin)
+		copy(genome[begin:end], b[begin:end])
+	case OperatorGaussian:
+		for i := range genome {
+			if rng.Float64() < rate {
+				genome[i] += float32(p.Sigma * rng.NormFloat64())
+			}
+		}
+	case OperatorFlip:
+		for i := range genome {
+			if rng.Float64() < rate {
+				genome[i] = -genome[i]
+			}
+		}
+	}
+}
+
+// OperatorMetrics are the offspring of an operator in a generation
+type OperatorMetrics struct {
+	// Name is the name of the operator
+	Name string `json:"name"`
+	// Offspring is the number of offspring produced by the operator
+	Offspring int `json:"offspring"`
+	// Elites is the number of the offspring selected as elites
+	Elites int `json:"elites"`
+	// Improvements is the number of the offspring better than the best before the generation
+	Improvements int `json:"improvements"`
+}
@@ -0,0 +1,51 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+)
+
+func TestOperators(t *testing.T) {
+	operators := Operators{Uniform: .25, Block: .125, Gaussian: .125, Flip: .25, Sigma: .1}
+	if counts := operators.counts(48); counts != [OperatorTotal]int{12, 12, 6, 6, 12} {
+		t.Fatalf("wrong operator counts %v", counts)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	elites := [][]float32{{1, 1, 1, 1}, {-1, -1, -1, -1}}
+	genome := make([]float32, 4)
+	operators.Rate = 1
+	operators.apply(OperatorFlip, rng, elites[:1], nil, genome)
+	for _, value := range genome {
+		if value != -1 {
+			t.Fatalf("flip should flip every gene %v", genome)
+		}
+	}
+	operators.apply(OperatorBlock, rng, elites, []int{0, 1, 0, 1}, genome)
+	if genome[0] != genome[2] || genome[1] != genome[3] {
+		t.Fatalf("blockwise crossover should keep the blocks %v", genome)
+	}
+
+	bits := Bits{N: 16}
+	optimizer := NewOptimizer(rng, 16, 16, 64, 16, OneMax(16).Fitness)
+	optimizer.Operators = Operators{Uniform: .25, Flip: .25}
+	optimizer.Run(context.Background(), 8, nil)
+	total := 0
+	for _, op := range optimizer.Metrics.Operators {
+		total += op.Offspring
+		if op.Elites > op.Offspring || op.Improvements > op.Offspring {
+			t.Fatalf("the %s operator has more elites or improvements than offspring", op.Name)
+		}
+	}
+	if total != optimizer.Born() || optimizer.Metrics.Operators[OperatorFlip].Offspring != 12 {
+		t.Fatalf("wrong number of offspring %d", total)
+	}
+	if best := optimizer.Best(); best.Fitness > 2 {
+		t.Fatalf("onemax should be nearly solved %v", bits.Decode(best.Genome))
+	}
+}
@@ -0,0 +1,394 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package eda implements a gaussian estimation of distribution optimizer
+package eda
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// ErrBudget is returned when the evaluation budget is exhausted
+var ErrBudget = errors.New("evaluation budget exhausted")
+
+// Fitness computes the fitness of a genome, lower is better
+type Fitness func(g []float32) float64
+
+// StochasticFitness computes a noisy fitness of a genome from the individual's random stream
+type StochasticFitness func(g []float32, rng *rand.Rand) float64
+
+// Individual is a member of the population
+type Individual struct {
+	Genome     []float32
+	Fitness    float64
+	Objectives []float64
+	// Samples is the number of noisy fitness samples averaged into Fitness
+	Samples int
+	// Variance is the variance of the noisy fitness samples
+	Variance float64
+	// Operator is the operator that produced the individual
+	Operator int
+	// Screened is set when the surrogate discarded the individual without evaluating it
+	Screened bool
+}
+
+// Optimizer is a partitioned multivariate gaussian estimation of distribution optimizer
+type Optimizer struct {
+	// Width is the width of the genome
+	Width int
+	// Partition is the width of each gaussian model
+	Partition int
+	// Population is the size of the population
+	Population int
+	// Cut is the number of elites used to fit the models
+	Cut int
+	// Cutoff is the cost cutoff for fitting the models
+	Cutoff float64
+	// Eta is the learning rate for fitting the models
+	Eta float64
+	// Linkage groups correlated genome positions instead of grouping them randomly
+	Linkage bool
+	// MaxEvals is the maximum number of fitness evaluations, 0 is unlimited
+	MaxEvals int
+	// Name is the name of the optimizer
+	Name string
+	// Problem identifies the fitness function to an Evaluator, Name is used when empty
+	Problem string
+	// Sampler is the search distribution, the default is Partitioned
+	Sampler Sampler
+	// Fitness is the fitness function
+	Fitness Fitness
+	// Stochastic is used instead of Fitness when set
+	Stochastic StochasticFitness
+	// Evaluator evaluates the offspring in batches instead of the fitness functions when set
+	Evaluator Evaluator
+	// Objectives selects the elites by pareto dominance when set, the
+	// first objective is used as the fitness
+	Objectives Objectives
+	// Restart is the restart strategy
+	Restart Restart
+	// Operators are the proportions of the offspring produced by genetic operators
+	Operators Operators
+	// Noise is the handling of a noisy Stochastic fitness
+	Noise Noise
+	// Screening evaluates only the offspring a surrogate ranks as most promising
+	Screening Screening
+	// Niching is the diversity preservation scheme used to select the elites
+	Niching Niching
+	// Archive collects distinct optima when set
+	Archive *Archive
+	// OnRestart is called with the reason after each restart
+	OnRestart func(o *Optimizer, reason string)
+	// Adaptation adapts the population and the fraction of elites
+	Adaptation Adaptation
+	// OnAdapt is called with the reason after each adaptation
+	OnAdapt func(o *Optimizer, reason string)
+
+	// Rng is the random number generator
+	Rng *rand.Rand
+	// Source is the source of Rng, if set its position is checkpointed
+	Source *Source
+	// State are the elites
+	State [][]float32
+	// Pop is the population sorted by fitness after each step
+	Pop []Individual
+	// Translate maps each genome position to its model in the last step
+	Translate []int
+	// Generation is the number of steps taken
+	Generation int
+	// Age is the number of steps since the last restart
+	Age int
+	// Evals is the number of fitness evaluations
+	Evals int
+	// Restarts is the number of restarts
+	Restarts int
+	// Champion is the best individual found over all restarts
+	Champion Individual
+	// Diagnostics are the convergence diagnostics of the last step
+	Diagnostics Diagnostics
+	// Metrics is the record of the last step
+	Metrics Metrics
+}
+
+// NewOptimizer creates a new optimizer with a random initial state
+func NewOptimizer(rng *rand.Rand, width, partition, population, cut int, fitness Fitness) *Optimizer {
+	state := make([][]float32, cut)
+	for i := range state {
+		for range width {
+			state[i] = append(state[i], float32(rng.NormFloat64()))
+		}
+	}
+	return &Optimizer{
+		Width:      width,
+		Partition:  partition,
+		Population: population,
+		Cut:        cut,
+		Cutoff:     .0001,
+		Eta:        1.0e-1,
+		Name:       "eda",
+		Fitness:    fitness,
+		Rng:        rng,
+		State:      state,
+		Pop:        make([]Individual, population),
+	}
+}
+
+// Resize changes the population size and the number of elites, starting over with random elites
+func (o *Optimizer) Resize(population, cut int) {
+	o.Population, o.Cut = population, cut
+	o.reset()
+}
+
+// Models is the number of gaussian models
+func (o *Optimizer) Models() int {
+	models := o.Width / o.Partition
+	if models < 1 {
+		models = 1
+	}
+	return models
+}
+
+// Born is the number of individuals evaluated by the next step
+func (o *Optimizer) Born() int {
+	if o.Age > 0 {
+		return o.Population - o.Cut
+	}
+	return o.Population
+}
+
+// Cost is the most fitness evaluations the next step can make, a noisy fitness
+// samples each offspring and each re-evaluated elite Samples times
+func (o *Optimizer) Cost() int {
+	evaluated := o.Born()
+	if !o.rounds() {
+		return evaluated
+	}
+	if o.Noise.Reevaluate {
+		evaluated = o.Population
+	}
+	return evaluated * max(o.Noise.Samples, 1)
+}
+
+// rounds is true when a noisy fitness is sampled in rounds once all the offspring are sampled
+func (o *Optimizer) rounds() bool {
+	return o.Stochastic != nil && o.Evaluator == nil && o.Objectives == nil && o.Noise.active()
+}
+
+// Groups returns the genome positions of each model in the last step
+func (o *Optimizer) Groups() [][]int {
+	groups := make([][]int, o.Models())
+	for i, t := range o.Translate {
+		groups[t] = append(groups[t], i)
+	}
+	return groups
+}
+
+// Best returns the best individual found
+func (o *Optimizer) Best() Individual {
+	return o.Champion
+}
+
+// Step runs one generation of the optimizer, an error of the evaluator leaves the elites unchanged
+func (o *Optimizer) Step() error {
+	rng, width := o.Rng, o.Width
+	if o.Sampler == nil {
+		o.Sampler = &Partitioned{}
+	}
+	start := time.Now()
+	o.Sampler.Fit(o)
+	fit := time.Since(start)
+
+	start = time.Now()
+	born := o.Pop[o.Population-o.Born():]
+	// the offspring are only screened once the surrogate has as many individuals as elites to learn from
+	screening := &o.Screening
+	screen := screening.Surrogate != nil && o.Objectives == nil && o.Age > 0 && len(screening.genomes) >= o.Cut
+	var predict Fitness
+	if screen {
+		predict = screening.Surrogate.Train(screening.genomes, screening.fitness)
+	}
+	noisy := o.rounds()
+	predicted, rngs := make([]float64, len(born)), make([]*rand.Rand, len(born))
+	learn := func(ii int, seed int64) {
+		rng := rand.New(rand.NewSource(seed))
+		vector := make([]float32, width)
+		op := o.Operators.operator(ii, len(born))
+		if op != OperatorSample {
+			o.Operators.apply(op, rng, o.State, o.Translate, vector)
+		} else if indexed, ok := o.Sampler.(IndexedSampler); ok {
+			indexed.SampleIndex(rng, ii, vector)
+		} else {
+			o.Sampler.Sample(rng, vector)
+		}
+		born[ii].Genome = vector
+		born[ii].Operator = op
+		born[ii].Screened = false
+		rngs[ii] = rng
+		switch {
+		case screen:
+			// the offspring are evaluated once they are all ranked by the surrogate
+			predicted[ii] = predict(vector)
+		case o.Evaluator == nil && !noisy:
+			o.evaluate(&born[ii], rng)
+		}
+	}
+	streams := seeds(rng, len(born))
+	parallel(len(born), func(ii int) {
+		learn(ii, streams[ii])
+	})
+	evaluated := make([]int, len(born))
+	for ii := range evaluated {
+		evaluated[ii] = ii
+	}
+	if screen {
+		sort.SliceStable(evaluated, func(i, j int) bool {
+			return predicted[evaluated[i]] < predicted[evaluated[j]]
+		})
+		n := min(max(1, int(math.Ceil(screening.Fraction*float64(len(born))))), len(born))
+		// the discarded offspring can not become elites
+		for _, ii := range evaluated[n:] {
+			born[ii].Fitness = math.Inf(1)
+			born[ii].Screened = true
+		}
+		evaluated = evaluated[:n]
+	}
+	evals := len(evaluated)
+	switch {
+	case noisy:
+		evals = o.noisy(born, evaluated, rngs)
+	case screen || o.Evaluator != nil:
+		err := o.evaluateAll(born, evaluated, rngs)
+		if err != nil {
+			return err
+		}
+	}
+	accuracy := 0.0
+	if screening.Surrogate != nil {
+		actual, estimates, individuals := []float64{}, []float64{}, []Individual{}
+		for _, ii := range evaluated {
+			actual = append(actual, born[ii].Fitness)
+			estimates = append(estimates, predicted[ii])
+			individuals = append(individuals, born[ii])
+		}
+		if screen {
+			accuracy = Spearman(estimates, actual)
+		}
+		screening.remember(individuals)
+	}
+	evaluate := time.Since(start)
+
+	operators := make([]OperatorMetrics, OperatorTotal)
+	offspring := make(map[*float32]bool, len(born))
+	for op := range operators {
+		operators[op].Name = OperatorNames[op]
+	}
+	for _, individual := range born {
+		operators[individual.Operator].Offspring++
+		if o.Age > 0 && individual.Fitness < o.Diagnostics.Best {
+			operators[individual.Operator].Improvements++
+		}
+		offspring[&individual.Genome[0]] = true
+	}
+	o.selectElites(len(born))
+	for _, elite := range o.Pop[:o.Cut] {
+		if elite.Genome != nil && offspring[&elite.Genome[0]] {
+			operators[elite.Operator].Elites++
+		}
+	}
+	for ii := range o.State {
+		copy(o.State[ii], o.Pop[ii].Genome)
+	}
+	best := o.Pop[0]
+	for _, individual := range o.Pop[1:] {
+		if individual.Fitness < best.Fitness {
+			best = individual
+		}
+	}
+	if o.Champion.Genome == nil || best.Fitness < o.Champion.Fitness {
+		o.Champion = best
+	}
+	if o.Archive != nil {
+		o.Archive.Collect(o.Pop)
+	}
+	improved := o.Age == 0 || best.Fitness < o.Diagnostics.Best
+	o.Generation++
+	o.Age++
+	o.Evals += evals
+	reason := o.diagnose(best.Fitness, improved)
+	o.measure(fit, evaluate)
+	o.Metrics.Surrogate = accuracy
+	o.Metrics.Operators = operators
+	if reason != "" {
+		o.restart(reason)
+	} else if reason := o.adapt(); reason != "" && o.OnAdapt != nil {
+		o.OnAdapt(o, reason)
+	}
+	return nil
+}
+
+// evaluate computes the fitness of an individual with the fitness functions
+func (o *Optimizer) evaluate(individual *Individual, rng *rand.Rand) {
+	switch {
+	case o.Objectives != nil:
+		individual.Objectives = o.Objectives(individual.Genome)
+		individual.Fitness = individual.Objectives[0]
+	case o.Stochastic != nil:
+		individual.Fitness = o.Stochastic(individual.Genome, rng)
+	default:
+		individual.Fitness = o.Fitness(individual.Genome)
+	}
+}
+
+// evaluateAll computes the fitness of the indexed offspring with the evaluator in
+// batches or with the fitness functions in parallel
+func (o *Optimizer) evaluateAll(born []Individual, index []int, rngs []*rand.Rand) error {
+	if o.Evaluator != nil {
+		genomes := make([][]float32, len(index))
+		for i, ii := range index {
+			genomes[i] = born[ii].Genome
+		}
+		problem := o.Problem
+		if problem == "" {
+			problem = o.Name
+		}
+		fitness, err := o.Evaluator.Evaluate(problem, genomes)
+		if err != nil {
+			return err
+		}
+		for i, ii := range index {
+			born[ii].Fitness = fitness[i]
+		}
+		return nil
+	}
+	parallel(len(index), func(i int) {
+		o.evaluate(&born[index[i]], rngs[index[i]])
+	})
+	return nil
+}
+
+// Run steps the optimizer for the given number of iterations, calling
+// callback after each step until it returns true. Run stops between
+// generations when the context is done or the evaluation budget is exhausted.
+func (o *Optimizer) Run(ctx context.Context, iterations int, callback func(o *Optimizer) bool) error {
+	for o.Generation < iterations {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if o.MaxEvals > 0 && o.Evals+o.Cost() > o.MaxEvals {
+			return ErrBudget
+		}
+		if err := o.Step(); err != nil {
+			return err
+		}
+		if callback != nil && callback(o) {
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/pointlander/entity/vector"
+)
+
+func sphere(g []float32) float64 {
+	sum := 0.0
+	for _, value := range g {
+		sum += float64(value * value)
+	}
+	return sum
+}
+
+func TestOptimizer(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	optimizer := NewOptimizer(rng, 8, 4, 128, 16, sphere)
+	first := 0.0
+	optimizer.Run(context.Background(), 16, func(o *Optimizer) bool {
+		if o.Generation == 1 {
+			first = o.Best().Fitness
+		}
+		return false
+	})
+	if optimizer.Generation != 16 {
+		t.Fatalf("generation should be 16 but is %d", optimizer.Generation)
+	}
+	if best := optimizer.Best().Fitness; best >= first {
+		t.Fatalf("fitness did not improve %f >= %f", best, first)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	optimizer := NewOptimizer(rng, 8, 4, 64, 8, sphere)
+	optimizer.MaxEvals = 64 + 2*56
+	err := optimizer.Run(context.Background(), 16, nil)
+	if err != ErrBudget {
+		t.Fatalf("budget should be exhausted but got %v", err)
+	}
+	if optimizer.Evals != optimizer.MaxEvals || optimizer.Generation != 3 {
+		t.Fatalf("%d evaluations in %d generations", optimizer.Evals, optimizer.Generation)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	optimizer = NewOptimizer(rng, 8, 4, 64, 8, sphere)
+	err = optimizer.Run(ctx, 16, func(o *Optimizer) bool {
+		cancel()
+		return false
+	})
+	if err != context.Canceled || optimizer.Generation != 1 {
+		t.Fatalf("run should be canceled after 1 generation: %v %d", err, optimizer.Generation)
+	}
+}
+
+func TestDeterministic(t *testing.T) {
+	defer func(deterministic bool) {
+		Deterministic = deterministic
+	}(Deterministic)
+	Deterministic = true
+	run := func() *Optimizer {
+		optimizer := NewOptimizer(rand.New(rand.NewSource(1)), 8, 4, 64, 8, nil)
+		optimizer.Stochastic = func(g []float32, rng *rand.Rand) float64 {
+			return sphere(g) + rng.Float64()
+		}
+		optimizer.Run(context.Background(), 8, nil)
+		return optimizer
+	}
+	a, b := run(), run()
+	for i := range a.Pop {
+		if a.Pop[i].Fitness != b.Pop[i].Fitness {
+			t.Fatalf("runs differ at %d: %f != %f", i, a.Pop[i].Fitness, b.Pop[i].Fitness)
+		}
+	}
+}
+
+func TestBitPattern(t *testing.T) {
+	defer func(deterministic, scalar bool) {
+		Deterministic, vector.Scalar = deterministic, scalar
+	}(Deterministic, vector.Scalar)
+	Deterministic, vector.Scalar = true, true
+	optimizer := NewOptimizer(rand.New(rand.NewSource(1)), 8, 4, 64, 8, Rosenbrock(8).Fitness)
+	optimizer.Run(context.Background(), 8, nil)
+	// the hash of the bits of the population is the same on every machine, even
+	// when the compiler fuses multiplies and adds
+	hash := fnv.New64a()
+	for _, individual := range optimizer.Pop {
+		binary.Write(hash, binary.LittleEndian, math.Float64bits(individual.Fitness))
+		for _, value := range individual.Genome {
+			binary.Write(hash, binary.LittleEndian, math.Float32bits(value))
+		}
+	}
+	if sum := hash.Sum64(); sum != 0xceffa44ca792909e {
+		t.Fatalf("the population bits changed %#x", sum)
+	}
+}
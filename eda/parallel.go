@@ -0,0 +1,46 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"math/rand"
+	"runtime"
+)
+
+// parallel calls f for each index below n with at most one goroutine per cpu in flight
+func parallel(n int, f func(i int)) {
+	done := make(chan bool, 8)
+	run := func(i int) {
+		f(i)
+		done <- true
+	}
+	i, flight, cpus := 0, 0, runtime.NumCPU()
+	for i < n && flight < cpus {
+		go run(i)
+		flight++
+		i++
+	}
+	for i < n {
+		<-done
+		flight--
+
+		go run(i)
+		flight++
+		i++
+	}
+	for range flight {
+		<-done
+	}
+}
+
+// seeds draws n seeds from rng in order, so the goroutines of parallel get the same
+// random streams however they are scheduled
+func seeds(rng *rand.Rand, n int) []int64 {
+	s := make([]int64, n)
+	for i := range s {
+		s[i] = rng.Int63()
+	}
+	return s
+}
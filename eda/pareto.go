@@ -0,0 +1,172 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Objectives computes the objectives of a genome, all of which are minimized
+type Objectives func(g []float32) []float64
+
+// Dominates is true if a is no worse than b in every objective and better in one
+func Dominates(a, b []float64) bool {
+	better := false
+	for i, value := range a {
+		if value > b[i] {
+			return false
+		}
+		if value < b[i] {
+			better = true
+		}
+	}
+	return better
+}
+
+// NonDominated sorts the population into fronts of non-dominated individuals
+func NonDominated(pop []Individual) [][]int {
+	dominated := make([][]int, len(pop))
+	count := make([]int, len(pop))
+	fronts := [][]int{{}}
+	for i := range pop {
+		for ii := range pop {
+			if Dominates(pop[i].Objectives, pop[ii].Objectives) {
+				dominated[i] = append(dominated[i], ii)
+			} else if Dominates(pop[ii].Objectives, pop[i].Objectives) {
+				count[i]++
+			}
+		}
+		if count[i] == 0 {
+			fronts[0] = append(fronts[0], i)
+		}
+	}
+	for len(fronts[len(fronts)-1]) > 0 {
+		next := []int{}
+		for _, i := range fronts[len(fronts)-1] {
+			for _, ii := range dominated[i] {
+				count[ii]--
+				if count[ii] == 0 {
+					next = append(next, ii)
+				}
+			}
+		}
+		fronts = append(fronts, next)
+	}
+	return fronts[:len(fronts)-1]
+}
+
+// Crowding computes the crowding distance of the individuals of a front
+func Crowding(pop []Individual, front []int) []float64 {
+	distance := make([]float64, len(front))
+	if len(front) == 0 {
+		return distance
+	}
+	index := make([]int, len(front))
+	for objective := range pop[front[0]].Objectives {
+		for i := range index {
+			index[i] = i
+		}
+		sort.Slice(index, func(i, j int) bool {
+			return pop[front[index[i]]].Objectives[objective] < pop[front[index[j]]].Objectives[objective]
+		})
+		min := pop[front[index[0]]].Objectives[objective]
+		max := pop[front[index[len(index)-1]]].Objectives[objective]
+		distance[index[0]], distance[index[len(index)-1]] = math.Inf(1), math.Inf(1)
+		if max == min {
+			continue
+		}
+		for i := 1; i < len(index)-1; i++ {
+			distance[index[i]] += (pop[front[index[i+1]]].Objectives[objective] -
+				pop[front[index[i-1]]].Objectives[objective]) / (max - min)
+		}
+	}
+	return distance
+}
+
+// selectPareto orders the population by non-dominated front and then by decreasing crowding distance
+func (o *Optimizer) selectPareto() {
+	pop := make([]Individual, 0, len(o.Pop))
+	for _, front := range NonDominated(o.Pop) {
+		distance := Crowding(o.Pop, front)
+		index := make([]int, len(front))
+		for i := range index {
+			index[i] = i
+		}
+		sort.SliceStable(index, func(i, j int) bool {
+			return distance[index[i]] > distance[index[j]]
+		})
+		for _, i := range index {
+			pop = append(pop, o.Pop[front[i]])
+		}
+	}
+	copy(o.Pop, pop)
+}
+
+// Front returns the distinct non-dominated individuals of the population
+func (o *Optimizer) Front() []Individual {
+	front := []Individual{}
+	fronts := NonDominated(o.Pop)
+	if len(fronts) == 0 {
+		return front
+	}
+next:
+	for _, i := range fronts[0] {
+		for _, individual := range front {
+			same := true
+			for ii, value := range individual.Objectives {
+				if value != o.Pop[i].Objectives[ii] {
+					same = false
+					break
+				}
+			}
+			if same {
+				continue next
+			}
+		}
+		front = append(front, o.Pop[i])
+	}
+	sort.Slice(front, func(i, j int) bool {
+		return front[i].Objectives[0] < front[j].Objectives[0]
+	})
+	return front
+}
+
+// WriteFront writes the objectives and genomes of a front as csv
+func WriteFront(output io.Writer, front []Individual) error {
+	writer := csv.NewWriter(output)
+	if len(front) > 0 {
+		header := []string{}
+		for i := range front[0].Objectives {
+			header = append(header, fmt.Sprintf("objective_%d", i))
+		}
+		for i := range front[0].Genome {
+			header = append(header, fmt.Sprintf("gene_%d", i))
+		}
+		err := writer.Write(header)
+		if err != nil {
+			return err
+		}
+	}
+	for _, individual := range front {
+		record := []string{}
+		for _, value := range individual.Objectives {
+			record = append(record, strconv.FormatFloat(value, 'g', -1, 64))
+		}
+		for _, value := range individual.Genome {
+			record = append(record, strconv.FormatFloat(float64(value), 'g', -1, 32))
+		}
+		err := writer.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,55 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestPareto(t *testing.T) {
+	individual := func(objectives ...float64) Individual {
+		return Individual{Genome: []float32{0}, Objectives: objectives}
+	}
+	pop := []Individual{
+		individual(1, 4),
+		individual(2, 2),
+		individual(4, 1),
+		individual(3, 3),
+		individual(5, 5),
+	}
+	fronts := NonDominated(pop)
+	if len(fronts) != 3 || len(fronts[0]) != 3 || fronts[1][0] != 3 || fronts[2][0] != 4 {
+		t.Fatalf("wrong fronts %v", fronts)
+	}
+	distance := Crowding(pop, fronts[0])
+	if !math.IsInf(distance[0], 1) || !math.IsInf(distance[2], 1) || distance[1] != 2 {
+		t.Fatalf("wrong crowding distance %v", distance)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	optimizer := NewOptimizer(rng, 2, 2, 64, 16, nil)
+	optimizer.Objectives = func(g []float32) []float64 {
+		a, b := float64(g[0]), float64(g[1])
+		return []float64{a*a + b*b, (a-2)*(a-2) + b*b}
+	}
+	optimizer.Run(context.Background(), 8, nil)
+	front := optimizer.Front()
+	if len(front) < 8 {
+		t.Fatalf("the front should have at least 8 individuals but has %d", len(front))
+	}
+	buffer := bytes.Buffer{}
+	err := WriteFront(&buffer, front)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(buffer.String(), "\n"); lines != len(front)+1 {
+		t.Fatalf("the csv should have %d lines but has %d", len(front)+1, lines)
+	}
+}
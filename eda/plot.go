@@ -0,0 +1,187 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"math"
+	"os"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+	"gonum.org/v1/plot/vg"
+	vgdraw "gonum.org/v1/plot/vg/draw"
+	"gonum.org/v1/plot/vg/vgimg"
+)
+
+// MaxFrames is the maximum number of frames in the elite animation
+const MaxFrames = 128
+
+// Plot records the progress of an optimizer for plotting
+type Plot struct {
+	// Name is the name of the optimizer
+	Name string
+	// Best is the best fitness of each generation
+	Best plotter.XYs
+	// Median is the median fitness of each generation
+	Median plotter.XYs
+	// Frames are the elites of each generation projected on their first two principal components
+	Frames []plotter.XYs
+}
+
+// Record records the last step of the optimizer
+func (p *Plot) Record(o *Optimizer) {
+	generation := float64(o.Metrics.Generation)
+	p.Best = append(p.Best, plotter.XY{X: generation, Y: o.Metrics.Best})
+	p.Median = append(p.Median, plotter.XY{X: generation, Y: o.Metrics.Median})
+	p.Frames = append(p.Frames, PCA(o.State))
+}
+
+// PCA projects the vectors on their first two principal components
+func PCA(vectors [][]float32) plotter.XYs {
+	points := make(plotter.XYs, len(vectors))
+	if len(vectors) == 0 {
+		return points
+	}
+	width := len(vectors[0])
+	centered := make([][]float64, len(vectors))
+	mean := make([]float64, width)
+	for _, v := range vectors {
+		for i, value := range v {
+			mean[i] += float64(value) / float64(len(vectors))
+		}
+	}
+	for i, v := range vectors {
+		centered[i] = make([]float64, width)
+		for ii, value := range v {
+			centered[i][ii] = float64(value) - mean[ii]
+		}
+	}
+	project := func(x, v []float64) float64 {
+		sum := 0.0
+		for i, value := range x {
+			sum += float64(value * v[i])
+		}
+		return sum
+	}
+	normalize := func(v []float64) {
+		norm := math.Sqrt(project(v, v))
+		if norm == 0 {
+			return
+		}
+		for i := range v {
+			v[i] /= norm
+		}
+	}
+	components := make([][]float64, 2)
+	for c := range components {
+		v := make([]float64, width)
+		for i := range v {
+			v[i] = float64((i+c)%width + 1)
+		}
+		for range 64 {
+			next := make([]float64, width)
+			for _, x := range centered {
+				s := project(x, v)
+				for i, value := range x {
+					next[i] += float64(s * value)
+				}
+			}
+			for _, component := range components[:c] {
+				s := project(next, component)
+				for i, value := range component {
+					next[i] -= float64(s * value)
+				}
+			}
+			normalize(next)
+			v = next
+		}
+		largest := 0
+		for i, value := range v {
+			if math.Abs(value) > math.Abs(v[largest]) {
+				largest = i
+			}
+		}
+		if v[largest] < 0 {
+			for i := range v {
+				v[i] = -v[i]
+			}
+		}
+		components[c] = v
+	}
+	for i, x := range centered {
+		points[i] = plotter.XY{X: project(x, components[0]), Y: project(x, components[1])}
+	}
+	return points
+}
+
+// Fitness saves the best and median fitness per generation to a png
+func (p *Plot) Fitness(path string) error {
+	graph := plot.New()
+	graph.Title.Text = fmt.Sprintf("%s fitness", p.Name)
+	graph.X.Label.Text = "generation"
+	graph.Y.Label.Text = "fitness"
+	graph.Legend.Top = true
+	for i, series := range []plotter.XYs{p.Best, p.Median} {
+		line, err := plotter.NewLine(series)
+		if err != nil {
+			return err
+		}
+		line.Color = plotutil.Color(i)
+		graph.Add(line)
+		graph.Legend.Add([]string{"best", "median"}[i], line)
+	}
+	return graph.Save(8*vg.Inch, 8*vg.Inch, path)
+}
+
+// Animate saves the projected elites of each generation to an animated gif
+func (p *Plot) Animate(path string) error {
+	if len(p.Frames) == 0 {
+		return nil
+	}
+	min, max := plotter.XY{X: math.Inf(1), Y: math.Inf(1)}, plotter.XY{X: math.Inf(-1), Y: math.Inf(-1)}
+	for _, frame := range p.Frames {
+		for _, point := range frame {
+			min.X, min.Y = math.Min(min.X, point.X), math.Min(min.Y, point.Y)
+			max.X, max.Y = math.Max(max.X, point.X), math.Max(max.Y, point.Y)
+		}
+	}
+	step := (len(p.Frames) + MaxFrames - 1) / MaxFrames
+	animation := &gif.GIF{}
+	for i := 0; i < len(p.Frames); i += step {
+		graph := plot.New()
+		graph.Title.Text = fmt.Sprintf("%s elites generation %d", p.Name, int(p.Best[i].X))
+		graph.X.Label.Text = "pc 1"
+		graph.Y.Label.Text = "pc 2"
+		graph.X.Min, graph.X.Max = min.X, max.X
+		graph.Y.Min, graph.Y.Max = min.Y, max.Y
+		scatter, err := plotter.NewScatter(p.Frames[i])
+		if err != nil {
+			return err
+		}
+		scatter.GlyphStyle.Radius = vg.Length(2)
+		scatter.GlyphStyle.Shape = vgdraw.CircleGlyph{}
+		graph.Add(scatter)
+
+		canvas := vgimg.New(4*vg.Inch, 4*vg.Inch)
+		graph.Draw(vgdraw.New(canvas))
+		img := canvas.Image()
+		frame := image.NewPaletted(img.Bounds(), palette.Plan9)
+		draw.Draw(frame, img.Bounds(), img, image.Point{}, draw.Src)
+		animation.Image = append(animation.Image, frame)
+		animation.Delay = append(animation.Delay, 10)
+	}
+	output, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+	return gif.EncodeAll(output, animation)
+}
@@ -0,0 +1,45 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+func TestPCA(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	vectors := make([][]float32, 256)
+	for i := range vectors {
+		a, b := rng.NormFloat64()*8, rng.NormFloat64()*2
+		vectors[i] = []float32{float32(a + b), float32(a - b), float32(rng.NormFloat64() * .1)}
+	}
+	points := PCA(vectors)
+	variance := [2]float64{}
+	for _, point := range points {
+		variance[0] += point.X * point.X / float64(len(points))
+		variance[1] += point.Y * point.Y / float64(len(points))
+	}
+	if math.Abs(variance[0]-128) > 24 || math.Abs(variance[1]-8) > 2 {
+		t.Fatalf("wrong principal component variances %v", variance)
+	}
+
+	optimizer := NewOptimizer(rng, 8, 8, 64, 16, sphere)
+	history := Plot{Name: "sphere"}
+	optimizer.Run(context.Background(), 4, func(o *Optimizer) bool {
+		history.Record(o)
+		return false
+	})
+	dir := t.TempDir()
+	if err := history.Fitness(filepath.Join(dir, "fitness.png")); err != nil {
+		t.Fatal(err)
+	}
+	if err := history.Animate(filepath.Join(dir, "elites.gif")); err != nil {
+		t.Fatal(err)
+	}
+}
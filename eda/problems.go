@@ -0,0 +1,212 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Problem is a black box test problem
+type Problem struct {
+	// Name is the name of the problem
+	Name string
+	// Width is the width of the genome
+	Width int
+	// Fitness is the fitness function
+	Fitness Fitness
+	// Target is the fitness at or below which the problem is solved
+	Target float64
+}
+
+// Sphere is the sum of squares with the optimum at the origin
+func Sphere(dimension int) Problem {
+	return Problem{
+		Name:  "sphere",
+		Width: dimension,
+		Fitness: func(g []float32) float64 {
+			fitness := 0.0
+			for _, value := range g {
+				fitness += float64(float64(value) * float64(value))
+			}
+			return fitness
+		},
+		Target: 1e-4,
+	}
+}
+
+// Rosenbrock is the banana valley with the optimum at one
+func Rosenbrock(dimension int) Problem {
+	return Problem{
+		Name:  "rosenbrock",
+		Width: dimension,
+		Fitness: func(g []float32) float64 {
+			fitness := 0.0
+			for i := 0; i < len(g)-1; i++ {
+				x, y := float64(g[i]), float64(g[i+1])
+				fitness += float64(100*(y-float64(x*x))*(y-float64(x*x))) + float64((1-x)*(1-x))
+			}
+			return fitness
+		},
+		Target: 1e-2,
+	}
+}
+
+// Rastrigin is the highly multimodal cosine modulated sphere
+func Rastrigin(dimension int) Problem {
+	return Problem{
+		Name:  "rastrigin",
+		Width: dimension,
+		Fitness: func(g []float32) float64 {
+			fitness := 10 * float64(len(g))
+			for _, value := range g {
+				x := float64(value)
+				fitness += float64(x*x) - float64(10*math.Cos(2*math.Pi*x))
+			}
+			return fitness
+		},
+		Target: 1e-2,
+	}
+}
+
+// Ackley is the multimodal exponential bowl
+func Ackley(dimension int) Problem {
+	return Problem{
+		Name:  "ackley",
+		Width: dimension,
+		Fitness: func(g []float32) float64 {
+			squares, cosines := 0.0, 0.0
+			for _, value := range g {
+				x := float64(value)
+				squares += float64(x * x)
+				cosines += math.Cos(2 * math.Pi * x)
+			}
+			n := float64(len(g))
+			return float64(-20*math.Exp(-.2*math.Sqrt(squares/n))) - math.Exp(cosines/n) + 20 + math.E
+		},
+		Target: 1e-2,
+	}
+}
+
+// OneMax is the number of unset bits
+func OneMax(dimension int) Problem {
+	bits := Bits{N: dimension}
+	return Problem{
+		Name:  "onemax",
+		Width: bits.Width(),
+		Fitness: Decoded(bits, func(value []bool) float64 {
+			fitness := 0.0
+			for _, bit := range value {
+				if !bit {
+					fitness++
+				}
+			}
+			return fitness
+		}),
+		Target: 0,
+	}
+}
+
+// Trap is the deceptive trap of concatenated k bit blocks, each block leads away
+// from its optimum of all bits set, k has to divide the dimension
+func Trap(dimension, k int) (Problem, error) {
+	if k < 1 || dimension < k || dimension%k != 0 {
+		return Problem{}, fmt.Errorf("the trap blocks of %d bits don't divide the dimension %d", k, dimension)
+	}
+	bits := Bits{N: dimension}
+	return Problem{
+		Name:  fmt.Sprintf("trap-%d", k),
+		Width: bits.Width(),
+		Fitness: Decoded(bits, func(value []bool) float64 {
+			fitness := 0.0
+			for i := 0; i < len(value); i += k {
+				u := 0
+				for _, bit := range value[i : i+k] {
+					if bit {
+						u++
+					}
+				}
+				if u == k {
+					continue
+				}
+				fitness += float64(u + 1)
+			}
+			return fitness
+		}),
+		Target: 0,
+	}, nil
+}
+
+// NK is an nk landscape where each bit interacts with the k bits that follow it. The
+// neighborhoods do not wrap around, so the optimum is found with dynamic programming and
+// the fitness is the distance to it.
+func NK(rng *rand.Rand, dimension, k int) (Problem, error) {
+	if dimension < 1 || k < 0 {
+		return Problem{}, fmt.Errorf("the nk landscape needs a dimension of at least 1 and k of at least 0, not %d and %d", dimension, k)
+	}
+	n := dimension
+	if k >= n {
+		k = n - 1
+	}
+	tables := make([][]float64, n)
+	for i := range tables {
+		tables[i] = make([]float64, 1<<(min(i+k, n-1)-i+1))
+		for ii := range tables[i] {
+			tables[i][ii] = rng.Float64()
+		}
+	}
+	value := func(bits []bool) float64 {
+		sum := 0.0
+		for i, table := range tables {
+			index := 0
+			for _, bit := range bits[i : min(i+k, n-1)+1] {
+				index <<= 1
+				if bit {
+					index |= 1
+				}
+			}
+			sum += table[index]
+		}
+		return sum / float64(n)
+	}
+
+	// the state is the last k bits, the contribution of bit j-k is complete once bit j is set
+	mask := 1<<k - 1
+	best := make([]float64, 1<<k)
+	for j := k; j < n; j++ {
+		next := make([]float64, 1<<k)
+		for i := range next {
+			next[i] = math.Inf(-1)
+		}
+		for state, v := range best {
+			for bit := range 2 {
+				full := state<<1 | bit
+				if w := v + tables[j-k][full]; w > next[full&mask] {
+					next[full&mask] = w
+				}
+			}
+		}
+		best = next
+	}
+	optimum := math.Inf(-1)
+	for state, v := range best {
+		for i := n - k; i < n; i++ {
+			v += tables[i][state&(1<<(n-i)-1)]
+		}
+		optimum = math.Max(optimum, v)
+	}
+	optimum /= float64(n)
+
+	bits := Bits{N: n}
+	return Problem{
+		Name:  fmt.Sprintf("nk-%d", k),
+		Width: bits.Width(),
+		Fitness: Decoded(bits, func(bits []bool) float64 {
+			return optimum - value(bits)
+		}),
+		Target: 1e-9,
+	}, nil
+}
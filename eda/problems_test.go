@@ -0,0 +1,61 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestProblems(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	optima := map[string][]float32{
+		"sphere":     {0, 0, 0, 0, 0, 0},
+		"rosenbrock": {1, 1, 1, 1, 1, 1},
+		"rastrigin":  {0, 0, 0, 0, 0, 0},
+		"ackley":     {0, 0, 0, 0, 0, 0},
+		"onemax":     {1, 1, 1, 1, 1, 1},
+		"trap-3":     {1, 1, 1, 1, 1, 1},
+	}
+	trap, err := Trap(6, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, problem := range []Problem{Sphere(6), Rosenbrock(6), Rastrigin(6), Ackley(6), OneMax(6), trap} {
+		if fitness := problem.Fitness(optima[problem.Name]); math.Abs(fitness) > 1e-9 {
+			t.Fatalf("%s should be 0 at the optimum but is %f", problem.Name, fitness)
+		}
+	}
+	if trap.Fitness([]float32{-1, -1, -1, 1, 1, -1}) != 4 {
+		t.Fatalf("trap should be deceptive")
+	}
+	for _, size := range [][2]int{{0, 3}, {7, 3}, {6, 0}} {
+		if _, err := Trap(size[0], size[1]); err == nil {
+			t.Fatalf("trap %d of %d bit blocks should be rejected", size[0], size[1])
+		}
+	}
+	if _, err := NK(rng, 0, 2); err == nil {
+		t.Fatalf("an empty nk landscape should be rejected")
+	}
+
+	for k := range 4 {
+		nk, err := NK(rng, 10, k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		best := math.Inf(1)
+		g := make([]float32, nk.Width)
+		for x := range 1 << nk.Width {
+			for i := range g {
+				g[i] = float32(x>>i&1)*2 - 1
+			}
+			best = math.Min(best, nk.Fitness(g))
+		}
+		if math.Abs(best) > 1e-12 {
+			t.Fatalf("the nk-%d optimum is off by %g", k, best)
+		}
+	}
+}
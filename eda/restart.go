@@ -0,0 +1,127 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/pointlander/entity/matrix"
+)
+
+const (
+	// RestartNone never restarts
+	RestartNone = iota
+	// RestartRandom re-randomizes the elites
+	RestartRandom
+	// RestartIPOP re-randomizes the elites and doubles the population
+	RestartIPOP
+)
+
+// Restart is the restart strategy of the optimizer
+type Restart struct {
+	// Strategy is the restart strategy
+	Strategy int
+	// Patience is the number of generations without improvement before a restart, 0 disables
+	Patience int
+	// MinTrace restarts when the trace of the fitted covariance falls below it
+	MinTrace float64
+	// MinDiversity restarts when the elite diversity falls below it
+	MinDiversity float64
+	// MaxPopulation bounds the population growth of IPOP, 0 is unbounded
+	MaxPopulation int
+}
+
+// Diagnostics are the convergence diagnostics of the last generation
+type Diagnostics struct {
+	// Best is the best fitness since the last restart
+	Best float64
+	// Stagnation is the number of generations since the best fitness improved
+	Stagnation int
+	// Trace is the trace of the fitted covariance
+	Trace float64
+	// Diversity is the mean distance of the elites to their centroid
+	Diversity float64
+}
+
+// Diversity is the mean euclidean distance of the elites to their centroid
+func Diversity[T matrix.Float](state [][]T) float64 {
+	if len(state) == 0 {
+		return 0
+	}
+	centroid := make([]float64, len(state[0]))
+	for _, elite := range state {
+		for i, value := range elite {
+			centroid[i] += float64(value)
+		}
+	}
+	for i := range centroid {
+		centroid[i] /= float64(len(state))
+	}
+	diversity := 0.0
+	for _, elite := range state {
+		sum := 0.0
+		for i, value := range elite {
+			diff := float64(value) - centroid[i]
+			sum += float64(diff * diff)
+		}
+		diversity += math.Sqrt(sum)
+	}
+	return diversity / float64(len(state))
+}
+
+// diagnose updates the diagnostics and returns the reason for a restart if one is needed
+func (o *Optimizer) diagnose(best float64, improved bool) string {
+	if improved {
+		o.Diagnostics.Best = best
+		o.Diagnostics.Stagnation = 0
+	} else {
+		o.Diagnostics.Stagnation++
+	}
+	o.Diagnostics.Trace = o.Sampler.Trace()
+	o.Diagnostics.Diversity = Diversity(o.State)
+
+	r := o.Restart
+	if r.Strategy == RestartNone {
+		return ""
+	}
+	switch {
+	case r.Patience > 0 && o.Diagnostics.Stagnation >= r.Patience:
+		return fmt.Sprintf("no improvement for %d generations", o.Diagnostics.Stagnation)
+	case o.Diagnostics.Trace < r.MinTrace:
+		return fmt.Sprintf("covariance trace %g < %g", o.Diagnostics.Trace, r.MinTrace)
+	case o.Diagnostics.Diversity < r.MinDiversity:
+		return fmt.Sprintf("elite diversity %g < %g", o.Diagnostics.Diversity, r.MinDiversity)
+	}
+	return ""
+}
+
+// restart re-randomizes the elites and grows the population for IPOP
+func (o *Optimizer) restart(reason string) {
+	if o.Restart.Strategy == RestartIPOP &&
+		(o.Restart.MaxPopulation == 0 || 2*o.Population <= o.Restart.MaxPopulation) {
+		o.Population *= 2
+		o.Cut *= 2
+	}
+	o.reset()
+	o.Restarts++
+	if o.OnRestart != nil {
+		o.OnRestart(o, reason)
+	}
+}
+
+// reset re-randomizes the elites and empties the population
+func (o *Optimizer) reset() {
+	o.State = make([][]float32, o.Cut)
+	for i := range o.State {
+		for range o.Width {
+			o.State[i] = append(o.State[i], float32(o.Rng.NormFloat64()))
+		}
+	}
+	o.Pop = make([]Individual, o.Population)
+	o.Age = 0
+	o.Diagnostics = Diagnostics{}
+	o.Adaptation.best, o.Adaptation.trace = nil, nil
+}
@@ -0,0 +1,37 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+)
+
+func TestRestart(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	optimizer := NewOptimizer(rng, 8, 4, 32, 4, func(g []float32) float64 {
+		return 1
+	})
+	optimizer.Restart = Restart{
+		Strategy:      RestartIPOP,
+		Patience:      2,
+		MaxPopulation: 128,
+	}
+	reasons := []string{}
+	optimizer.OnRestart = func(o *Optimizer, reason string) {
+		reasons = append(reasons, reason)
+	}
+	optimizer.Run(context.Background(), 12, nil)
+	if optimizer.Restarts != 4 || len(reasons) != 4 {
+		t.Fatalf("there should be 4 restarts but there are %d: %v", optimizer.Restarts, reasons)
+	}
+	if optimizer.Population != 128 || optimizer.Cut != 16 || len(optimizer.State) != 16 {
+		t.Fatalf("population should double up to 128 but is %d with %d elites", optimizer.Population, optimizer.Cut)
+	}
+	if optimizer.Diagnostics.Trace != 0 || optimizer.Diagnostics.Stagnation != 0 {
+		t.Fatalf("diagnostics should be reset after a restart: %+v", optimizer.Diagnostics)
+	}
+}
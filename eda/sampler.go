@@ -0,0 +1,235 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"math/rand"
+
+	"github.com/pointlander/entity/matrix"
+)
+
+// Sampler is a search distribution that is fit to the elites and sampled for new individuals
+type Sampler interface {
+	// Fit fits the distribution to the elites of the optimizer
+	Fit(o *Optimizer)
+	// Sample fills genome with a sample from the distribution
+	Sample(rng *rand.Rand, genome []float32)
+	// Trace is the trace of the covariance of the distribution
+	Trace() float64
+}
+
+// IndexedSampler is a Sampler that needs the index of each offspring, such as for mirrored sampling
+type IndexedSampler interface {
+	Sampler
+	// SampleIndex fills genome with the sample of the offspring at index
+	SampleIndex(rng *rand.Rand, index int, genome []float32)
+}
+
+// StatefulSampler is a Sampler whose state is saved in the checkpoints, so a resumed
+// run continues from the same distribution
+type StatefulSampler interface {
+	Sampler
+	// State encodes the state of the distribution fit to the optimizer
+	State(o *Optimizer) ([]byte, error)
+	// Restore decodes the state of the distribution after the optimizer is loaded
+	Restore(o *Optimizer, state []byte) error
+}
+
+// partitionedState is the state of Partitioned in a checkpoint
+type partitionedState struct {
+	Translate []int
+	A, U      []matrix.Matrix[float32]
+	Mean      []float32
+}
+
+// Partitioned randomly partitions the genome and fits a multivariate gaussian to each partition
+type Partitioned struct {
+	Translate []int
+	A         []matrix.Matrix[float32]
+	U         []matrix.Matrix[float32]
+	// Mean is the mean of the models scattered into the genome positions
+	Mean []float32
+	// Rate is the learning rate that blends the fitted models into the previous models
+	// like the rank-mu update of CMA-ES, 0 fits the models from scratch
+	Rate float64
+	// Ranked weights the elites by their rank instead of equally
+	Ranked bool
+}
+
+// Fit fits a multivariate gaussian to each partition of the elites
+func (p *Partitioned) Fit(o *Optimizer) {
+	rng, width, models := o.Rng, o.Width, o.Models()
+	var translate []int
+	if o.Linkage {
+		translate = Linkage(rng, o.State, models)
+	} else {
+		translate = make([]int, width)
+		for i := range translate {
+			translate[i] = i % models
+		}
+		rng.Shuffle(width, func(i, j int) {
+			translate[i], translate[j] = translate[j], translate[i]
+		})
+	}
+	o.Translate = translate
+	// the previous models are blended into the new ones until the elites are reset
+	previous := p.Rate > 0 && p.Rate < 1 && o.Age > 0 && len(p.Mean) == width
+	weights := make([]float64, len(o.State))
+	for i := range weights {
+		weights[i] = 1 / float64(len(weights))
+	}
+	if p.Ranked {
+		weights = o.ranked()
+	}
+	// position is the index of each genome position in its previous model
+	position, counts := make([]int, width), make([]int, len(p.A))
+	if previous {
+		for i, t := range p.Translate {
+			position[i] = counts[t]
+			counts[t]++
+		}
+	}
+	covariance := func(i, j int) float32 {
+		t := p.Translate[i]
+		if p.Translate[j] != t {
+			return 0
+		}
+		a, sum := p.A[t], float32(0)
+		for k := range a.Cols {
+			sum += float32(a.Data[position[i]*a.Cols+k] * a.Data[position[j]*a.Cols+k])
+		}
+		return sum
+	}
+	a, u := make([]matrix.Matrix[float32], models), make([]matrix.Matrix[float32], models)
+	process := func(ii int, seed int64) {
+		rng := rand.New(rand.NewSource(seed))
+		s := make([][]float32, len(o.State))
+		for iii := range o.State {
+			for iv, t := range translate {
+				if t == ii {
+					s[iii] = append(s[iii], o.State[iii][iv])
+				}
+			}
+		}
+		name := fmt.Sprintf("%s_%d", o.Name, o.Generation)
+		if !previous && !p.Ranked {
+			a[ii], _, u[ii] = NewMultiVariateGaussian(o.Cutoff, o.Eta, false, false, rng, name, len(s[0]), s)
+			return
+		}
+
+		genes := []int{}
+		for iv, t := range translate {
+			if t == ii {
+				genes = append(genes, iv)
+			}
+		}
+		size := len(genes)
+		avg := make([]float32, size)
+		for iii, elite := range s {
+			for i, value := range elite {
+				avg[i] += float32(float32(weights[iii]) * value)
+			}
+		}
+		// the rank-mu update centers the elites on the previous mean
+		origin := avg
+		if previous {
+			origin = make([]float32, size)
+			for i, gene := range genes {
+				origin[i] = p.Mean[gene]
+			}
+		}
+		cov := make([][]float32, size)
+		for i := range cov {
+			cov[i] = make([]float32, size)
+		}
+		for iii, elite := range s {
+			for i, v := range elite {
+				for j, vv := range elite {
+					cov[i][j] += float32(float32(weights[iii]) * (v - origin[i]) * (vv - origin[j]))
+				}
+			}
+		}
+		if previous {
+			rate := float32(p.Rate)
+			for i := range cov {
+				for j := range cov[i] {
+					cov[i][j] = float32((1-rate)*covariance(genes[i], genes[j])) + float32(rate*cov[i][j])
+				}
+				avg[i] = float32((1-rate)*origin[i]) + float32(rate*avg[i])
+			}
+		}
+		a[ii], _, u[ii] = NewGaussian(o.Cutoff, o.Eta, false, false, rng, name, size, avg, cov)
+	}
+	partitions := seeds(rng, models)
+	parallel(models, func(ii int) {
+		process(ii, partitions[ii])
+	})
+	p.Translate, p.A, p.U = translate, a, u
+	p.Mean = make([]float32, width)
+	for iii := range u {
+		index := 0
+		for iv, t := range translate {
+			if t == iii {
+				p.Mean[iv] = u[iii].Data[index]
+				index++
+			}
+		}
+	}
+}
+
+// State encodes the partitions and their models, which the next fit blends into
+func (p *Partitioned) State(o *Optimizer) ([]byte, error) {
+	buffer := bytes.Buffer{}
+	err := gob.NewEncoder(&buffer).Encode(partitionedState{
+		Translate: p.Translate,
+		A:         p.A,
+		U:         p.U,
+		Mean:      p.Mean,
+	})
+	return buffer.Bytes(), err
+}
+
+// Restore decodes the partitions and their models
+func (p *Partitioned) Restore(o *Optimizer, state []byte) error {
+	s := partitionedState{}
+	err := gob.NewDecoder(bytes.NewReader(state)).Decode(&s)
+	if err != nil {
+		return err
+	}
+	p.Translate, p.A, p.U, p.Mean = s.Translate, s.A, s.U, s.Mean
+	o.Translate = s.Translate
+	return nil
+}
+
+// Sample samples each partition and scatters the samples into the genome
+func (p *Partitioned) Sample(rng *rand.Rand, genome []float32) {
+	for iii := range p.A {
+		g := matrix.NewMatrix[float32](p.A[iii].Cols, 1)
+		for range p.A[iii].Cols {
+			g.Data = append(g.Data, float32(rng.NormFloat64()))
+		}
+		vec, index := p.A[iii].MulT(g).Add(p.U[iii]), 0
+		for iv, t := range p.Translate {
+			if t == iii {
+				genome[iv] = vec.Data[index]
+				index++
+			}
+		}
+	}
+}
+
+// Trace is the sum of the traces of A*A^T of the partitions
+func (p *Partitioned) Trace() float64 {
+	trace := 0.0
+	for _, a := range p.A {
+		for _, value := range a.Data {
+			trace += float64(value * value)
+		}
+	}
+	return trace
+}
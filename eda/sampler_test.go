@@ -0,0 +1,95 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+func TestIncremental(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	partitioned := &Partitioned{Rate: .25}
+	optimizer := NewOptimizer(rng, 8, 4, 32, 8, sphere)
+	optimizer.Sampler = partitioned
+	optimizer.Run(context.Background(), 1, nil)
+	mean := append([]float32{}, partitioned.Mean...)
+	for _, elite := range optimizer.State {
+		for i := range elite {
+			elite[i] = 1
+		}
+	}
+	partitioned.Fit(optimizer)
+	for i, value := range partitioned.Mean {
+		if expected := .75*mean[i] + .25; math.Abs(float64(value-expected)) > 1e-5 {
+			t.Fatalf("the mean should be blended %f != %f", value, expected)
+		}
+	}
+
+	optimizer = NewOptimizer(rng, 16, 8, 64, 4, sphere)
+	optimizer.Sampler = &Partitioned{Rate: .5, Ranked: true}
+	optimizer.Run(context.Background(), 16, nil)
+	if best := optimizer.Best().Fitness; best > 2 {
+		t.Fatalf("the sphere should improve with 4 elites but the best is %f", best)
+	}
+}
+
+func TestPartitionedResume(t *testing.T) {
+	defer func(deterministic bool) {
+		Deterministic = deterministic
+	}(Deterministic)
+	Deterministic = true
+	path := filepath.Join(t.TempDir(), "partitioned.checkpoint")
+	source := NewSource(1)
+	optimizer := NewOptimizer(rand.New(source), 8, 4, 32, 8, sphere)
+	optimizer.Source = source
+	optimizer.Sampler = &Partitioned{Rate: .5, Ranked: true}
+	optimizer.Niching = Niching{Scheme: NichingClearing, Radius: .5}
+	optimizer.Run(context.Background(), 4, nil)
+	err := optimizer.Save(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	optimizer.Run(context.Background(), 8, nil)
+
+	source = NewSource(1)
+	resumed := NewOptimizer(rand.New(source), 8, 4, 32, 8, sphere)
+	resumed.Source = source
+	partitioned := &Partitioned{Rate: .5, Ranked: true}
+	resumed.Sampler = partitioned
+	resumed.Niching = Niching{Scheme: NichingClearing, Radius: .5}
+	err = resumed.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(partitioned.Mean) != 8 || len(partitioned.A) != resumed.Models() {
+		t.Fatal("the models were not restored")
+	}
+	resumed.Run(context.Background(), 8, nil)
+	for i := range optimizer.Pop {
+		if optimizer.Pop[i].Fitness != resumed.Pop[i].Fitness {
+			t.Fatalf("resumed run differs at %d: %f != %f", i, optimizer.Pop[i].Fitness, resumed.Pop[i].Fitness)
+		}
+	}
+}
+
+func TestRanked(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	optimizer := NewOptimizer(rng, 4, 4, 16, 4, sphere)
+	optimizer.Run(context.Background(), 1, nil)
+	// niching can leave the elites out of fitness order
+	for i, j := range []int{2, 0, 3, 1} {
+		optimizer.Pop[i].Fitness = float64(j)
+	}
+	weights, ranked := Weights(4), optimizer.ranked()
+	for i, j := range []int{2, 0, 3, 1} {
+		if ranked[i] != weights[j] {
+			t.Fatalf("elite %d should have the weight of rank %d %f != %f", i, j, ranked[i], weights[j])
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"math/rand"
+)
+
+// Source is a random source that counts its draws so its position can be restored
+type Source struct {
+	source rand.Source64
+	seed   int64
+	draws  uint64
+}
+
+// NewSource creates a new source seeded with seed
+func NewSource(seed int64) *Source {
+	return &Source{
+		source: rand.NewSource(seed).(rand.Source64),
+		seed:   seed,
+	}
+}
+
+// Int63 returns a non-negative pseudo-random 63-bit integer
+func (s *Source) Int63() int64 {
+	s.draws++
+	return s.source.Int63()
+}
+
+// Uint64 returns a pseudo-random 64-bit integer
+func (s *Source) Uint64() uint64 {
+	s.draws++
+	return s.source.Uint64()
+}
+
+// Seed reseeds the source
+func (s *Source) Seed(seed int64) {
+	s.source.Seed(seed)
+	s.seed, s.draws = seed, 0
+}
+
+// Position returns the seed and the number of draws
+func (s *Source) Position() (seed int64, draws uint64) {
+	return s.seed, s.draws
+}
+
+// Restore restores the source to a position
+func (s *Source) Restore(seed int64, draws uint64) {
+	s.Seed(seed)
+	for range draws {
+		s.source.Uint64()
+	}
+	s.draws = draws
+}
@@ -0,0 +1,191 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"math"
+	"sort"
+)
+
+// Surrogate is a regression model of the fitness that is cheaper than the fitness
+type Surrogate interface {
+	// Train fits the model to the evaluated genomes and returns the prediction of the
+	// fitness, which is called from several goroutines
+	Train(genomes [][]float32, fitness []float64) Fitness
+}
+
+// Screening ranks the sampled offspring with a surrogate so that only the most
+// promising fraction is evaluated with the fitness, the rest are discarded
+type Screening struct {
+	// Surrogate is the model, screening is disabled when nil
+	Surrogate Surrogate
+	// Fraction is the fraction of the offspring that are evaluated
+	Fraction float64
+	// Memory is the number of recently evaluated individuals the surrogate is trained on
+	Memory int
+
+	genomes [][]float32
+	fitness []float64
+}
+
+// remember adds evaluated individuals to the memory, forgetting the oldest
+func (s *Screening) remember(individuals []Individual) {
+	for _, individual := range individuals {
+		s.genomes = append(s.genomes, append([]float32{}, individual.Genome...))
+		s.fitness = append(s.fitness, individual.Fitness)
+	}
+	if forget := len(s.genomes) - max(s.Memory, 1); forget > 0 {
+		s.genomes = append([][]float32{}, s.genomes[forget:]...)
+		s.fitness = append([]float64{}, s.fitness[forget:]...)
+	}
+}
+
+// KNN predicts the distance weighted mean fitness of the K nearest neighbors
+type KNN struct {
+	// K is the number of neighbors
+	K int
+}
+
+// Train returns the prediction from the nearest evaluated genomes
+func (k KNN) Train(genomes [][]float32, fitness []float64) Fitness {
+	type Neighbor struct {
+		Distance float64
+		Fitness  float64
+	}
+	return func(g []float32) float64 {
+		neighbors := make([]Neighbor, len(genomes))
+		for i, genome := range genomes {
+			neighbors[i] = Neighbor{Distance: Euclidean(g, genome), Fitness: fitness[i]}
+		}
+		sort.Slice(neighbors, func(i, j int) bool {
+			return neighbors[i].Distance < neighbors[j].Distance
+		})
+		sum, weights := 0.0, 0.0
+		for _, neighbor := range neighbors[:min(max(k.K, 1), len(neighbors))] {
+			if neighbor.Distance == 0 {
+				return neighbor.Fitness
+			}
+			sum += neighbor.Fitness / neighbor.Distance
+			weights += 1 / neighbor.Distance
+		}
+		if weights == 0 {
+			return 0
+		}
+		return sum / weights
+	}
+}
+
+// GP is gaussian process regression with a squared exponential kernel
+type GP struct {
+	// Length is the length scale of the kernel, the median distance between the genomes when 0
+	Length float64
+	// Noise is the variance of the fitness noise relative to the kernel variance
+	Noise float64
+}
+
+// Train solves for the weights of the kernels centered on the genomes and returns the
+// posterior mean of the fitness
+func (p GP) Train(genomes [][]float32, fitness []float64) Fitness {
+	n, mean := len(genomes), 0.0
+	for _, value := range fitness {
+		mean += value / float64(n)
+	}
+	length := p.Length
+	if length == 0 {
+		distances := []float64{}
+		for i := range genomes {
+			for j := i + 1; j < n; j++ {
+				distances = append(distances, Euclidean(genomes[i], genomes[j]))
+			}
+		}
+		sort.Float64s(distances)
+		if len(distances) > 0 {
+			length = distances[len(distances)/2]
+		}
+		if length == 0 {
+			length = 1
+		}
+	}
+	kernel := func(a, b []float32) float64 {
+		distance := Euclidean(a, b) / length
+		return math.Exp(-distance * distance / 2)
+	}
+
+	cov := make([][]float64, n)
+	for i := range cov {
+		cov[i] = make([]float64, n)
+		for j := range cov[i] {
+			cov[i][j] = kernel(genomes[i], genomes[j])
+		}
+		cov[i][i] += p.Noise
+	}
+	l, _, _ := Cholesky(false, n, make([]float64, n), cov)
+	// solve L*L^T*alpha = fitness - mean with forward and back substitution
+	y, alpha := make([]float64, n), make([]float64, n)
+	for i := range n {
+		sum := fitness[i] - mean
+		for k := range i {
+			sum -= float64(l.Data[i*n+k] * y[k])
+		}
+		y[i] = sum / l.Data[i*n+i]
+	}
+	for i := n - 1; i >= 0; i-- {
+		sum := y[i]
+		for k := i + 1; k < n; k++ {
+			sum -= float64(l.Data[k*n+i] * alpha[k])
+		}
+		alpha[i] = sum / l.Data[i*n+i]
+	}
+	return func(g []float32) float64 {
+		prediction := mean
+		for i, genome := range genomes {
+			prediction += float64(alpha[i] * kernel(g, genome))
+		}
+		return prediction
+	}
+}
+
+// Spearman is the rank correlation of two samples
+func Spearman(a, b []float64) float64 {
+	n := len(a)
+	if n < 2 {
+		return 0
+	}
+	rank := func(values []float64) []float64 {
+		index := make([]int, len(values))
+		for i := range index {
+			index[i] = i
+		}
+		sort.SliceStable(index, func(i, j int) bool {
+			return values[index[i]] < values[index[j]]
+		})
+		ranks := make([]float64, len(values))
+		for i := 0; i < len(index); {
+			// ties share their mean rank
+			j := i
+			for j < len(index) && values[index[j]] == values[index[i]] {
+				j++
+			}
+			for k := i; k < j; k++ {
+				ranks[index[k]] = float64(i+j-1) / 2
+			}
+			i = j
+		}
+		return ranks
+	}
+	x, y := rank(a), rank(b)
+	mean := float64(n-1) / 2
+	xy, xx, yy := 0.0, 0.0, 0.0
+	for i := range x {
+		dx, dy := x[i]-mean, y[i]-mean
+		xy += float64(dx * dy)
+		xx += float64(dx * dx)
+		yy += float64(dy * dy)
+	}
+	if xx == 0 || yy == 0 {
+		return 0
+	}
+	return xy / math.Sqrt(xx*yy)
+}
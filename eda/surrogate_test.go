@@ -0,0 +1,48 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eda
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestScreening(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	genomes, fitness := [][]float32{}, []float64{}
+	for range 128 {
+		g := []float32{float32(rng.NormFloat64()), float32(rng.NormFloat64())}
+		genomes, fitness = append(genomes, g), append(fitness, sphere(g))
+	}
+	for _, surrogate := range []Surrogate{KNN{K: 4}, GP{Noise: 1e-6}} {
+		predict := surrogate.Train(genomes, fitness)
+		predicted, actual := []float64{}, []float64{}
+		for range 64 {
+			g := []float32{float32(rng.NormFloat64()), float32(rng.NormFloat64())}
+			predicted, actual = append(predicted, predict(g)), append(actual, sphere(g))
+		}
+		if correlation := Spearman(predicted, actual); correlation < .9 {
+			t.Fatalf("%T should rank the sphere but the rank correlation is %f", surrogate, correlation)
+		}
+	}
+
+	optimizer := NewOptimizer(rng, 8, 8, 64, 16, sphere)
+	optimizer.Screening = Screening{Surrogate: KNN{K: 4}, Fraction: .25, Memory: 256}
+	optimizer.Run(context.Background(), 16, nil)
+	// the first generation evaluates everyone and the surrogate screens the rest
+	if evals := 64 + 15*12; optimizer.Evals != evals {
+		t.Fatalf("there should be %d evaluations but there are %d", evals, optimizer.Evals)
+	}
+	if optimizer.Metrics.Surrogate <= 0 {
+		t.Fatalf("the surrogate should rank the offspring but the rank correlation is %f", optimizer.Metrics.Surrogate)
+	}
+	for _, individual := range optimizer.Pop[:optimizer.Cut] {
+		if math.IsInf(individual.Fitness, 1) {
+			t.Fatal("a discarded offspring became an elite")
+		}
+	}
+}
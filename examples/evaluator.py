@@ -0,0 +1,42 @@
+#!/usr/bin/env python3
+# Copyright 2024 The Entity Authors. All rights reserved.
+# Use of this source code is governed by a BSD-style
+# license that can be found in the LICENSE file.
+
+# An external evaluator for -evaluator "python3 examples/evaluator.py".
+# Each line of stdin is a request {"id", "problem", "genomes"} and each
+# line written to stdout is the response {"id", "fitness"} or {"id", "error"}.
+# json has no numbers for the non-finite fitness, so they are the strings
+# "NaN", "+Inf" and "-Inf".
+
+import json
+import math
+import sys
+
+def sphere(genome):
+    return sum(x * x for x in genome)
+
+def rastrigin(genome):
+    return 10 * len(genome) + sum(x * x - 10 * math.cos(2 * math.pi * x) for x in genome)
+
+def value(x):
+    if math.isnan(x):
+        return "NaN"
+    if math.isinf(x):
+        return "+Inf" if x > 0 else "-Inf"
+    return x
+
+problems = {
+    "sphere": sphere,
+    "rastrigin": rastrigin,
+}
+
+for line in sys.stdin:
+    request = json.loads(line)
+    fitness = problems.get(request["problem"])
+    if fitness is None:
+        response = {"id": request["id"], "error": "unknown problem " + request["problem"]}
+    else:
+        response = {"id": request["id"], "fitness": [value(fitness(genome)) for genome in request["genomes"]]}
+    sys.stdout.write(json.dumps(response) + "\n")
+    sys.stdout.flush()
@@ -5,27 +5,24 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"math/big"
 	"math/rand"
-	"os"
-	"runtime"
-	"sort"
+
+	"github.com/pointlander/entity/eda"
 )
 
 // Factor factors a number
-func Factor() {
-	rng := rand.New(rand.NewSource(1))
-	type Number struct {
-		Number  Matrix[float32]
-		Fitness float64
-	}
-
+func Factor(ctx context.Context) {
+	source := eda.NewSource(*FlagSeed)
+	rng := rand.New(source)
 	const (
 		width      = 1024
-		models     = width / 64
-		iterations = 16
+		iterations = 1024 * 1024
+		patience   = 8
 		population = 1024
+		cut        = 8
 	)
 
 	numbers := [2]*big.Int{}
@@ -47,131 +44,62 @@ func Factor() {
 	target.Mul(numbers[0], numbers[1])
 	fmt.Println(numbers[0], numbers[1], target)
 
-	for {
-		fmt.Println("_______________")
-		state := make([][]float32, 8)
-		for i := range state {
-			for range width {
-				state[i] = append(state[i], float32(rng.NormFloat64()))
-			}
-		}
-		pop := make([]Number, population)
-
-		for i := 0; i < iterations; i++ {
-			graph := false //i == 0 || i == iterations-1
-			translate := make([]int, width)
-			for i := range translate {
-				translate[i] = i % models
-			}
-			rng.Shuffle(width, func(i, j int) {
-				translate[i], translate[j] = translate[j], translate[i]
-			})
-			var a, u [models]Matrix[float32]
-			done := make(chan bool, 8)
-			process := func(ii int, seed int64) {
-				rng := rand.New(rand.NewSource(seed))
-				s := make([][]float32, len(state))
-				for iii := range state {
-					for iv, t := range translate {
-						if t == ii {
-							s[iii] = append(s[iii], state[iii][iv])
-						}
-					}
-				}
-				a[ii], _, u[ii] = NewMultiVariateGaussian(.0001, 1.0e-1, graph, false, rng, fmt.Sprintf("number_%d", i), 64, s)
-				done <- true
-			}
-			ii, flight, cpus := 0, 0, runtime.NumCPU()
-			for ii < models && flight < cpus {
-				go process(ii, rng.Int63())
-				flight++
-				ii++
-			}
-			for ii < models {
-				<-done
-				flight--
-
-				go process(ii, rng.Int63())
-				flight++
-				ii++
-			}
-			for range flight {
-				<-done
-			}
+	codec := eda.Bits{N: width}
+	fitness := eda.Decoded(codec, func(bits []bool) float64 {
+		steps, _ := gcd(bits, target)
+		return float64(steps)
+	})
 
-			born := pop
-			if i > 0 {
-				born = pop[8:]
-			}
-			learn := func(ii int, seed int64) {
-				rng := rand.New(rand.NewSource(seed))
-				vector := NewMatrix[float32](width, 1)
-				vector.Data = make([]float32, width)
-				for iii := range a {
-					g := NewMatrix[float32](a[iii].Cols, 1)
-					for range a[iii].Cols {
-						g.Data = append(g.Data, float32(rng.NormFloat64()))
-					}
-					vec, index := a[iii].MulT(g).Add(u[iii]), 0
-					for iv, t := range translate {
-						if t == iii {
-							vector.Data[iv] = vec.Data[index]
-							index++
-						}
-					}
-				}
-				born[ii].Number = NewMatrix(width, 1, vector.Data...)
-				born[ii].Fitness = 0.0
-				number := big.NewInt(0)
-				for iii, bit := range born[ii].Number.Data {
-					if bit > 0 {
-						number.SetBit(number, iii, 1)
-					}
-
-				}
-				a := big.NewInt(0)
-				b := big.NewInt(0)
-				a.Set(number)
-				b.Set(target)
-				for b.Cmp(big.NewInt(0)) != 0 {
-					c := big.NewInt(0)
-					c.Mod(a, b)
-					a.Set(b)
-					b.Set(c)
-					born[ii].Fitness++
-				}
-				if a.Cmp(big.NewInt(1)) != 0 {
-					b.Set(target)
-					fmt.Println(target, "/", a, "=", b.Div(target, a))
-					os.Exit(0)
-				}
-				done <- true
-			}
-			ii, flight, cpus = 0, 0, runtime.NumCPU()
-			for ii < len(born) && flight < cpus {
-				go learn(ii, rng.Int63())
-				flight++
-				ii++
-			}
-			for ii < len(born) {
-				<-done
-				flight--
-
-				go learn(ii, rng.Int63())
-				flight++
-				ii++
+	optimizer := eda.NewOptimizer(rng, width, 64, population, cut, fitness)
+	optimizer.Name = "factor"
+	optimizer.Source = source
+	optimizer.Niching.Distance = eda.Hamming
+	optimizer.Restart = eda.Restart{
+		Strategy: eda.RestartRandom,
+		Patience: patience,
+	}
+	var factor *big.Int
+	Run(ctx, optimizer, iterations, func(o *eda.Optimizer) bool {
+		fmt.Println(o.Best().Fitness)
+		// the first proper divisor in the order of the population, so the factor is the same on every run
+		for _, individual := range o.Pop {
+			if len(individual.Genome) == 0 {
+				continue
 			}
-			for range flight {
-				<-done
+			_, divisor := gcd(codec.Decode(individual.Genome), target)
+			if divisor.Cmp(big.NewInt(1)) > 0 && divisor.Cmp(target) < 0 {
+				factor = divisor
+				return true
 			}
+		}
+		return false
+	})
+	if factor != nil {
+		b := big.NewInt(0)
+		fmt.Println(target, "/", factor, "=", b.Div(target, factor))
+	}
+}
 
-			sort.Slice(pop, func(i, j int) bool {
-				return pop[i].Fitness < pop[j].Fitness
-			})
-			for ii := range state {
-				copy(state[ii], pop[ii].Number.Data)
-			}
-			fmt.Println(pop[0].Fitness)
+// gcd is the number of steps of the euclidean algorithm and the greatest common divisor of
+// the number with the bits and the target
+func gcd(bits []bool, target *big.Int) (int, *big.Int) {
+	steps := 0
+	number := big.NewInt(0)
+	for i, bit := range bits {
+		if bit {
+			number.SetBit(number, i, 1)
 		}
 	}
+	a := big.NewInt(0)
+	b := big.NewInt(0)
+	a.Set(number)
+	b.Set(target)
+	for b.Cmp(big.NewInt(0)) != 0 {
+		c := big.NewInt(0)
+		c.Mod(a, b)
+		a.Set(b)
+		b.Set(c)
+		steps++
+	}
+	return steps, a
 }
@@ -6,17 +6,23 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"image"
 	"image/jpeg"
 	"math/rand"
 	"os"
 	"sort"
+	"time"
+
+	"github.com/pointlander/entity/eda"
+	"github.com/pointlander/entity/matrix"
 )
 
-// Image is the image model
-func Image() {
-	rng := rand.New(rand.NewSource(1))
+// Image is the image model, it stops between generations when the context is
+// done or the evaluation budget is exhausted
+func Image(ctx context.Context) {
+	rng := rand.New(rand.NewSource(*FlagSeed))
 	var state [8][][]float64
 	for i := range state {
 		state[i] = make([][]float64, 8)
@@ -28,21 +34,35 @@ func Image() {
 		}
 	}
 	type Entity struct {
-		Vector  [8]Matrix[float64]
+		Vector  [8]matrix.Matrix[float64]
 		Fitness float64
 	}
-	const iterations = 256
+	const (
+		iterations = 256
+		population = 256
+	)
 	for i := 0; i < iterations; i++ {
+		err := ctx.Err()
+		if *FlagMaxEvals > 0 && (i+1)*population > *FlagMaxEvals {
+			err = eda.ErrBudget
+		}
+		if err != nil {
+			fmt.Println("stopping image at generation", i, "after", i*population, "evaluations:", err)
+			return
+		}
 		graph := i == 0 || i == iterations-1
-		var a, u [8]Matrix[float64]
+		start := time.Now()
+		var a, u [8]matrix.Matrix[float64]
 		for ii := range a {
-			a[ii], _, u[ii] = NewMultiVariateGaussian[float64](.0001, 1.0e-1, graph, false, rng, fmt.Sprintf("entropy_%d", i), 64, state[ii])
+			a[ii], _, u[ii] = eda.NewMultiVariateGaussian[float64](.0001, 1.0e-1, graph, false, rng, fmt.Sprintf("entropy_%d", i), 64, state[ii])
 		}
-		pop := make([]Entity, 256)
+		fit := time.Since(start)
+		start = time.Now()
+		pop := make([]Entity, population)
 		for ii := range pop {
 			img := image.NewGray(image.Rect(0, 0, 8, 8))
 			for v := range a {
-				g := NewMatrix[float64](64, 1)
+				g := matrix.NewMatrix[float64](64, 1)
 				for range 8 {
 					g.Data = append(g.Data, rng.NormFloat64())
 				}
@@ -80,6 +100,7 @@ func Image() {
 			}
 			pop[ii].Fitness = math.Abs(fitness)*/
 		}
+		evaluate := time.Since(start)
 		sort.Slice(pop, func(i, j int) bool {
 			return pop[i].Fitness < pop[j].Fitness
 		})
@@ -91,6 +112,31 @@ func Image() {
 				}
 			}
 		}
+		if metrics != nil {
+			elites := make([][]float64, 8)
+			for v := range state {
+				for ii := range elites {
+					elites[ii] = append(elites[ii], state[v][ii]...)
+				}
+			}
+			fitness := make([]float64, len(pop))
+			for ii := range pop {
+				fitness[ii] = pop[ii].Fitness
+			}
+			m := eda.Metrics{
+				Name:       "image",
+				Generation: i + 1,
+				Diversity:  eda.Diversity(elites),
+				Fit:        fit,
+				Evaluate:   evaluate,
+				Evals:      (i + 1) * len(pop),
+			}
+			m.Measure(fitness)
+			err := metrics.Write(m)
+			if err != nil {
+				panic(err)
+			}
+		}
 		{
 			img := image.NewGray(image.Rect(0, 0, 8, 8))
 			for v := range a {
@@ -104,7 +150,7 @@ func Image() {
 					}
 				}
 			}
-			output, err := os.Create(fmt.Sprintf("img_%d.jpg", i))
+			output, err := os.Create(artifacts.Path(fmt.Sprintf("img_%d.jpg", i)))
 			if err != nil {
 				panic(err)
 			}
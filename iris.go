@@ -10,6 +10,9 @@ import (
 	"math/rand"
 	"runtime"
 	"time"
+
+	"github.com/pointlander/entity/eda"
+	"github.com/pointlander/entity/matrix"
 )
 
 // IrisModel the iris model
@@ -27,11 +30,11 @@ func IrisModel() {
 			}
 		}
 	}
-	rng := rand.New(rand.NewSource(1))
-	var A, AI, u [3]Matrix[float64]
+	rng := rand.New(rand.NewSource(*FlagSeed))
+	var A, AI, u [3]matrix.Matrix[float64]
 	cal := [][]float64{}
 	for i := range vectors {
-		A[i], AI[i], u[i] = NewMultiVariateGaussian[float64](-1, Eta, true, true, rng, Inverse[i], 4, vectors[i])
+		A[i], AI[i], u[i] = eda.NewMultiVariateGaussian[float64](-1, eda.Eta, true, true, rng, Inverse[i], 4, vectors[i])
 		diff := A[i].MulT(AI[i])
 		c := make([]float64, 5)
 		for ii, value := range diff.Data {
@@ -53,7 +56,7 @@ func IrisModel() {
 			rng := rand.New(rand.NewSource(seed))
 			var histogram [150][3]uint64
 			for i := range iris {
-				vector := NewMatrix[float64](4, 1)
+				vector := matrix.NewMatrix[float64](4, 1)
 				vector.Data = append(vector.Data, iris[i].Measures...)
 				min, index := math.MaxFloat64, 0
 				for ii := range AI {
@@ -129,7 +132,7 @@ func IrisModel() {
 			min, index := math.MaxFloat64, 0
 			for range 16 * 33 {
 				for ii := range A {
-					g := NewMatrix[float64](4, 1)
+					g := matrix.NewMatrix[float64](4, 1)
 					for iii := range 4 {
 						_ = iii
 						g.Data = append(g.Data, rng.NormFloat64())
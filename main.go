@@ -7,33 +7,29 @@ package main
 import (
 	"archive/zip"
 	"bytes"
+	"context"
 	"embed"
 	"encoding/csv"
 	"flag"
+	"fmt"
 	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
 	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/pointlander/entity/eda"
+	"github.com/pointlander/entity/vector"
 )
 
 const (
-	// B1 exponential decay of the rate for the first moment estimates
-	B1 = 0.8
-	// B2 exponential decay rate for the second-moment estimates
-	B2 = 0.89
-	// Eta is the learning rate
-	Eta = 1.0e-3
 	// Scale is the scale of the model
 	Scale = 128
 )
 
-const (
-	// StateM is the state for the mean
-	StateM = iota
-	// StateV is the state for the variance
-	StateV
-	// StateTotal is the total number of states
-	StateTotal
-)
-
 var Galaxies = [][]float64{
 	{2.48, 00, 42, 41.877, 40, 51, 54.71}, // M32
 	{2.69, 00, 40, 22.054, 41, 41, 08.04}, // M110
@@ -158,8 +154,134 @@ var (
 	FlagTransformer = flag.Bool("t", false, "transformer mode")
 	// FlagEntropy entropy mode
 	FlagEntropy = flag.Bool("e", false, "entropy mode")
+	// FlagBenchOpt benchmark the optimizer on the black box test problems
+	FlagBenchOpt = flag.Bool("bench-opt", false, "benchmark the optimizer on the black box test problems")
 	// FlagBuild build the model
 	FlagBuild = flag.Bool("build", false, "build the model")
+	// FlagCheckpoint checkpoint the optimizer every n generations
+	FlagCheckpoint = flag.Int("checkpoint", 0, "checkpoint the optimizer every n generations")
+	// FlagResume resume the optimizer from the last checkpoint
+	FlagResume = flag.Bool("resume", false, "resume the optimizer from the last checkpoint")
+	// FlagMaxEvals the maximum number of fitness evaluations
+	FlagMaxEvals = flag.Int("max-evals", 0, "the maximum number of fitness evaluations")
+	// FlagTimeout the wall clock limit for the run
+	FlagTimeout = flag.Duration("timeout", 0, "the wall clock limit for the run")
+	// FlagSeed the seed for the random number generator
+	FlagSeed = flag.Int64("seed", 1, "the seed for the random number generator")
+	// FlagDeterministic bit reproducible runs on every machine
+	FlagDeterministic = flag.Bool("deterministic", false, "bit reproducible runs on every machine")
+	// FlagLinkage learn the linkage between genome positions
+	FlagLinkage = flag.Bool("linkage", false, "learn the linkage between genome positions")
+	// FlagSampler the search distribution of the optimizer
+	FlagSampler = flag.String("sampler", "gaussian", "the search distribution of the optimizer: gaussian, network, cma or nes")
+	// FlagLearningRate blend the fitted gaussians into the previous ones with this learning rate
+	FlagLearningRate = flag.Float64("learning-rate", 0, "blend the fitted gaussians into the previous ones with this learning rate, 0 fits them from scratch")
+	// FlagRanked weight the elites by their rank when fitting the gaussians
+	FlagRanked = flag.Bool("ranked", false, "weight the elites by their rank when fitting the gaussians")
+	// FlagRestart the restart strategy
+	FlagRestart = flag.String("restart", "", "the restart strategy: none, random or ipop")
+	// FlagPatience restart after n generations without improvement
+	FlagPatience = flag.Int("patience", 16, "restart after n generations without improvement")
+	// FlagMinTrace restart when the trace of the fitted covariance is below this
+	FlagMinTrace = flag.Float64("min-trace", 0, "restart when the trace of the fitted covariance is below this")
+	// FlagMinDiversity restart when the elite diversity is below this
+	FlagMinDiversity = flag.Float64("min-diversity", 0, "restart when the elite diversity is below this")
+	// FlagMaxPopulation the largest population ipop restarts and adaptation grow to
+	FlagMaxPopulation = flag.Int("max-population", 64*1024, "the largest population ipop restarts and adaptation grow to")
+	// FlagAdapt adapt the population and the fraction of elites from the progress over n generations
+	FlagAdapt = flag.Int("adapt", 0, "adapt the population and the fraction of elites from the progress over n generations, 0 disables adaptation")
+	// FlagMinPopulation the smallest population adaptation shrinks to
+	FlagMinPopulation = flag.Int("min-population", 16, "the smallest population adaptation shrinks to")
+	// FlagMinRatio the smallest fraction of the population that are elites
+	FlagMinRatio = flag.Float64("min-ratio", .01, "the smallest fraction of the population that are elites")
+	// FlagMaxRatio the largest fraction of the population that are elites
+	FlagMaxRatio = flag.Float64("max-ratio", .5, "the largest fraction of the population that are elites")
+	// FlagGrowth the factor adaptation grows or shrinks the population by
+	FlagGrowth = flag.Float64("growth", 1.5, "the factor adaptation grows or shrinks the population by")
+	// FlagProgress the relative improvement over the adaptation window below which the search is stalled
+	FlagProgress = flag.Float64("progress", .01, "the relative improvement over the adaptation window below which the search is stalled")
+	// FlagNiching the niching scheme used to select the elites
+	FlagNiching = flag.String("niching", "", "the niching scheme used to select the elites: none, clearing, replacement or sharing")
+	// FlagRadius the niche radius
+	FlagRadius = flag.Float64("radius", 2, "the niche radius")
+	// FlagCapacity the number of winners of each niche for clearing
+	FlagCapacity = flag.Int("capacity", 1, "the number of winners of each niche for clearing")
+	// FlagOptima collect the distinct optima
+	FlagOptima = flag.Bool("optima", false, "collect the distinct optima")
+	// FlagPareto optimize multiple objectives and write the pareto front
+	FlagPareto = flag.Bool("pareto", false, "optimize multiple objectives and write the pareto front")
+	// FlagMetrics write the per generation metrics to a jsonl or csv file in the run directory
+	FlagMetrics = flag.String("metrics", "", "write the per generation metrics to a jsonl or csv file in the run directory")
+	// FlagPlot plot the fitness and the elites of each generation
+	FlagPlot = flag.Bool("plot", false, "plot the fitness and the elites of each generation")
+	// FlagDimension the dimension of the benchmark problems
+	FlagDimension = flag.Int("dimension", 16, "the dimension of the benchmark problems")
+	// FlagK the block size of trap and the epistasis of the nk landscape
+	FlagK = flag.Int("k", 4, "the block size of trap and the epistasis of the nk landscape")
+	// FlagProblems the comma separated benchmark problems
+	FlagProblems = flag.String("problems", "all", "the comma separated benchmark problems: sphere, rosenbrock, rastrigin, ackley, onemax, trap-k, nk-k or all")
+	// FlagSeeds the number of seeds of each benchmark problem
+	FlagSeeds = flag.Int("seeds", 8, "the number of seeds of each benchmark problem")
+	// FlagGenerations the maximum number of generations of each benchmark run
+	FlagGenerations = flag.Int("generations", 256, "the maximum number of generations of each benchmark run")
+	// FlagSpec the json experiment specification
+	FlagSpec = flag.String("spec", "", "the json experiment specification")
+	// FlagSweep run the sweep of the experiment specification
+	FlagSweep = flag.Bool("sweep", false, "run the sweep of the experiment specification")
+	// FlagRun the run directory
+	FlagRun = flag.String("run", "", "the run directory, a new directory under runs by default")
+	// FlagModel the model to load instead of the one in the latest run directory
+	FlagModel = flag.String("model", "", "the model to load instead of the one in the latest run directory")
+	// FlagEvaluator the command of an external evaluator speaking line delimited json
+	FlagEvaluator = flag.String("evaluator", "", "the command of an external evaluator speaking line delimited json")
+	// FlagWorkers the number of external evaluator processes
+	FlagWorkers = flag.Int("workers", runtime.NumCPU(), "the number of external evaluator processes")
+	// FlagBatch the maximum number of genomes sent to an evaluator at once
+	FlagBatch = flag.Int("batch", 64, "the maximum number of genomes sent to an evaluator at once")
+	// FlagIslands the number of islands evolved in parallel
+	FlagIslands = flag.Int("islands", 1, "the number of islands evolved in parallel")
+	// FlagTopology the migration topology of the islands
+	FlagTopology = flag.String("topology", "ring", "the migration topology of the islands: ring or full")
+	// FlagMigration migrate between the islands every n generations
+	FlagMigration = flag.Int("migration", 16, "migrate between the islands every n generations, 0 disables migration")
+	// FlagMigrants the number of individuals each island receives in a migration
+	FlagMigrants = flag.Int("migrants", 4, "the number of individuals each island receives in a migration")
+	// FlagUniform the proportion of the offspring produced by uniform crossover
+	FlagUniform = flag.Float64("uniform", 0, "the proportion of the offspring produced by uniform crossover")
+	// FlagBlock the proportion of the offspring produced by blockwise crossover
+	FlagBlock = flag.Float64("block", 0, "the proportion of the offspring produced by blockwise crossover")
+	// FlagMutate the proportion of the offspring produced by gaussian mutation
+	FlagMutate = flag.Float64("mutate", 0, "the proportion of the offspring produced by gaussian mutation")
+	// FlagFlip the proportion of the offspring produced by bit flip mutation
+	FlagFlip = flag.Float64("flip", 0, "the proportion of the offspring produced by bit flip mutation")
+	// FlagSigma the standard deviation of gaussian mutation
+	FlagSigma = flag.Float64("sigma", .1, "the standard deviation of gaussian mutation")
+	// FlagRate the probability that mutation changes a gene
+	FlagRate = flag.Float64("rate", 0, "the probability that mutation changes a gene, 1/width when 0")
+	// FlagSamples the number of samples of a noisy fitness averaged for each offspring
+	FlagSamples = flag.Int("samples", 1, "the number of samples of a noisy fitness averaged for each offspring")
+	// FlagReevaluate sample the noisy fitness of the elites again each generation
+	FlagReevaluate = flag.Bool("reevaluate", false, "sample the noisy fitness of the elites again each generation")
+	// FlagRacing drop the candidates worse than the elites by this many standard errors between samples
+	FlagRacing = flag.Float64("racing", 0, "drop the candidates worse than the elites by this many standard errors between samples, 0 disables racing")
+	// FlagCommon compare the candidates of a generation on common random numbers
+	FlagCommon = flag.Bool("common", false, "compare the candidates of a generation on common random numbers")
+	// FlagSurrogate the surrogate that screens the offspring before they are evaluated
+	FlagSurrogate = flag.String("surrogate", "", "the surrogate that screens the offspring before they are evaluated: knn or gp")
+	// FlagScreen the fraction of the offspring evaluated after screening
+	FlagScreen = flag.Float64("screen", .25, "the fraction of the offspring evaluated after screening")
+	// FlagMemory the number of recently evaluated individuals the surrogate is trained on
+	FlagMemory = flag.Int("memory", 512, "the number of recently evaluated individuals the surrogate is trained on")
+	// FlagNeighbors the number of neighbors of the knn surrogate
+	FlagNeighbors = flag.Int("neighbors", 8, "the number of neighbors of the knn surrogate")
+	// FlagCoordinator listen for evaluation workers on this tcp address
+	FlagCoordinator = flag.String("coordinator", "", "listen for evaluation workers on this tcp address")
+	// FlagWorker evaluate the batches of the coordinator at this tcp address
+	FlagWorker = flag.String("worker", "", "evaluate the batches of the coordinator at this tcp address")
+	// FlagWorkerTimeout the longest a worker may take for a batch before its batch is reassigned
+	FlagWorkerTimeout = flag.Duration("worker-timeout", 0, "the longest a worker may take for a batch before its batch is reassigned")
+	// FlagScalar force the scalar kernels
+	FlagScalar = flag.Bool("scalar", false, "force the scalar kernels")
 )
 
 //go:embed books/*
@@ -168,6 +290,129 @@ var Data embed.FS
 func main() {
 	flag.Parse()
 
+	if *FlagDeterministic {
+		eda.Deterministic = true
+		vector.Scalar = true
+	}
+	if *FlagScalar {
+		vector.Scalar = true
+	}
+
+	if *FlagSpec != "" {
+		LoadExperiment(*FlagSpec)
+	}
+
+	if *FlagWorker != "" {
+		Worker(*FlagWorker)
+		return
+	}
+
+	modes := []struct {
+		Name string
+		Set  bool
+	}{
+		{"iris", *FlagIris},
+		{"text", *FlagText},
+		{"image", *FlagImage},
+		{"rnn", *FlagRNN},
+		{"factor", *FlagFactor},
+		{"queens", *FlagQueens},
+		{"bf", *FlagBF},
+		{"ff", *FlagFF},
+		{"transformer", *FlagTransformer},
+		{"entropy", *FlagEntropy},
+		{"bench", *FlagBenchOpt},
+	}
+	mode := ""
+	for _, m := range modes {
+		if m.Set {
+			mode = m.Name
+			break
+		}
+	}
+	if *FlagSweep && experiment.Sweep != nil {
+		mode = "sweep_" + experiment.Sweep.Mode
+	}
+	if mode == "" {
+		return
+	}
+	if *FlagPareto && (*FlagEvaluator != "" || *FlagCoordinator != "") {
+		panic(fmt.Errorf("-pareto can not be used with an external evaluator, which only returns the fitness"))
+	}
+	// the workers can't draw the factor target from the rng of the run or reproduce the stochastic rnn
+	if *FlagCoordinator != "" {
+		switch strings.TrimPrefix(mode, "sweep_") {
+		case "factor", "rnn":
+			panic(fmt.Errorf("-coordinator can not be used with the %s mode", mode))
+		}
+	}
+	artifacts = NewArtifacts(*FlagRun, mode)
+	defer artifacts.Close()
+	eda.Output = artifacts.Dir
+	fmt.Println("run directory", artifacts.Dir)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// the first interrupt ends the run gracefully and a second one kills the process
+	context.AfterFunc(ctx, stop)
+	if *FlagTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *FlagTimeout)
+		defer cancel()
+	}
+
+	if *FlagEvaluator != "" {
+		pool, err := eda.NewSubprocess(*FlagEvaluator, *FlagWorkers, *FlagBatch)
+		if err != nil {
+			panic(err)
+		}
+		defer pool.Close()
+		evaluator = pool
+	}
+
+	if *FlagCoordinator != "" {
+		coordinator, err := eda.NewCoordinator(*FlagCoordinator, *FlagBatch)
+		if err != nil {
+			panic(err)
+		}
+		defer coordinator.Close()
+		coordinator.Timeout = *FlagWorkerTimeout
+		coordinator.OnWorker = func(addr string, connected bool) {
+			if connected {
+				fmt.Println("worker connected", addr)
+			} else {
+				fmt.Println("worker lost", addr)
+			}
+		}
+		// closing the coordinator ends an evaluation that is waiting for workers
+		context.AfterFunc(ctx, func() {
+			coordinator.Close()
+		})
+		fmt.Println("waiting for workers on", coordinator.Addr())
+		evaluator = coordinator
+	}
+
+	if *FlagMetrics != "" {
+		output, err := os.Create(artifacts.Path(filepath.Base(*FlagMetrics)))
+		if err != nil {
+			panic(err)
+		}
+		defer output.Close()
+		format := "jsonl"
+		if filepath.Ext(*FlagMetrics) == ".csv" {
+			format = "csv"
+		}
+		metrics, err = eda.NewMetricsWriter(output, format)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	if *FlagSweep {
+		RunSweep(ctx)
+		return
+	}
+
 	// +
 	if *FlagIris {
 		IrisModel()
@@ -182,48 +427,53 @@ func main() {
 
 	// +-
 	if *FlagImage {
-		Image()
+		Image(ctx)
 		return
 	}
 
 	// -
 	if *FlagRNN {
-		RNN()
+		RNN(ctx)
 		return
 	}
 
 	// +-
 	if *FlagFactor {
-		Factor()
+		Factor(ctx)
 		return
 	}
 
 	// +
 	if *FlagQueens {
-		Queens()
+		Queens(ctx)
 		return
 	}
 
 	// -
 	if *FlagBF {
-		BF()
+		BF(ctx)
 		return
 	}
 
 	// +
 	if *FlagFF {
-		FF()
+		FF(ctx)
 		return
 	}
 
 	// ?
 	if *FlagTransformer {
-		T()
+		T(ctx)
 		return
 	}
 
 	if *FlagEntropy {
-		Entropy()
+		Entropy(ctx)
+		return
+	}
+
+	if *FlagBenchOpt {
+		BenchOpt(ctx)
 		return
 	}
 }
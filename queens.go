@@ -5,27 +5,23 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
-	"runtime"
-	"sort"
+
+	"github.com/pointlander/entity/eda"
 )
 
-// Queens is the 8 queens problem
-func Queens() {
-	rng := rand.New(rand.NewSource(1))
-	fitness := func(g []float32) float64 {
+// QueensCodec encodes the row of the queen in each column
+var QueensCodec = eda.Repeat[int]{Codec: eda.Int{Min: 0, Max: 7}, N: 8}
+
+// QueensFitness is the number of queens attacking each other
+func QueensFitness() eda.Fitness {
+	return eda.Decoded(QueensCodec, func(queens []int) float64 {
 		fitness := 0.0
 		board := make([]float64, 8*8)
-		for x := 0; x < 8; x++ {
-			y := uint(0)
-			for yy := range 3 {
-				y <<= 1
-				if g[3*x+yy] > 0 {
-					y |= 1
-				}
-			}
-			board[y*8+uint(x)] = 1
+		for x, y := range queens {
+			board[y*8+x] = 1
 		}
 		sum := 0
 		for _, value := range board {
@@ -117,140 +113,59 @@ func Queens() {
 			}
 		}
 		return fitness
-	}
-	board := make([]float32, 8*3)
+	})
+}
+
+// Queens is the 8 queens problem
+func Queens(ctx context.Context) {
+	source := eda.NewSource(*FlagSeed)
+	rng := rand.New(source)
+	codec, fitness := QueensCodec, QueensFitness()
+	board := make([]float32, codec.Width())
 	for i := range board {
 		board[i] = float32(rng.NormFloat64())
 	}
 	fmt.Println(fitness(board))
 
-	type Number struct {
-		Number  Matrix[float32]
-		Fitness float64
-	}
-
 	const (
-		width      = 8 * 3
-		models     = width / (8 * 3)
 		iterations = 1024
 		population = 8 * 1024
 		cut        = 512
 	)
 
-	state := make([][]float32, cut)
-	for i := range state {
-		for range width {
-			state[i] = append(state[i], float32(rng.NormFloat64()))
-		}
-	}
-	pop := make([]Number, population)
-
-	for i := 0; i < iterations; i++ {
-		graph := false //i == 0 || i == iterations-1
-		translate := make([]int, width)
-		for i := range translate {
-			translate[i] = i % models
-		}
-		rng.Shuffle(width, func(i, j int) {
-			translate[i], translate[j] = translate[j], translate[i]
-		})
-		var a, u [models]Matrix[float32]
-		done := make(chan bool, 8)
-		process := func(ii int, seed int64) {
-			rng := rand.New(rand.NewSource(seed))
-			s := make([][]float32, len(state))
-			for iii := range state {
-				for iv, t := range translate {
-					if t == ii {
-						s[iii] = append(s[iii], state[iii][iv])
-					}
-				}
+	width := codec.Width()
+	optimizer := eda.NewOptimizer(rng, width, width, population, cut, fitness)
+	optimizer.Name = "queens"
+	optimizer.Source = source
+	optimizer.Niching.Distance = eda.Hamming
+	if *FlagOptima {
+		optimizer.Niching.Scheme = eda.NichingClearing
+		optimizer.Niching.Radius = 2
+		optimizer.Archive = &eda.Archive{
+			Target:   0,
+			Distance: eda.Hamming,
+		}
+		found := 0
+		Run(ctx, optimizer, iterations, func(o *eda.Optimizer) bool {
+			for _, optimum := range o.Archive.Optima[found:] {
+				fmt.Println(codec.Decode(optimum.Genome))
 			}
-			a[ii], _, u[ii] = NewMultiVariateGaussian(.0001, 1.0e-1, graph, false, rng, fmt.Sprintf("number_%d", i), 8*3, s)
-			done <- true
-		}
-		ii, flight, cpus := 0, 0, runtime.NumCPU()
-		for ii < models && flight < cpus {
-			go process(ii, rng.Int63())
-			flight++
-			ii++
-		}
-		for ii < models {
-			<-done
-			flight--
-
-			go process(ii, rng.Int63())
-			flight++
-			ii++
-		}
-		for range flight {
-			<-done
-		}
-
-		born := pop
-		if i > 0 {
-			born = pop[cut:]
-		}
-		learn := func(ii int, seed int64) {
-			rng := rand.New(rand.NewSource(seed))
-			vector := NewMatrix[float32](width, 1)
-			vector.Data = make([]float32, width)
-			for iii := range a {
-				g := NewMatrix[float32](a[iii].Cols, 1)
-				for range a[iii].Cols {
-					g.Data = append(g.Data, float32(rng.NormFloat64()))
-				}
-				vec, index := a[iii].MulT(g).Add(u[iii]), 0
-				for iv, t := range translate {
-					if t == iii {
-						vector.Data[iv] = vec.Data[index]
-						index++
-					}
-				}
-			}
-			born[ii].Number = NewMatrix(width, 1, vector.Data...)
-			born[ii].Fitness = float64(fitness(born[ii].Number.Data))
-			done <- true
-		}
-		ii, flight, cpus = 0, 0, runtime.NumCPU()
-		for ii < len(born) && flight < cpus {
-			go learn(ii, rng.Int63())
-			flight++
-			ii++
-		}
-		for ii < len(born) {
-			<-done
-			flight--
-
-			go learn(ii, rng.Int63())
-			flight++
-			ii++
-		}
-		for range flight {
-			<-done
-		}
-
-		sort.Slice(pop, func(i, j int) bool {
-			return pop[i].Fitness < pop[j].Fitness
+			found = len(o.Archive.Optima)
+			fmt.Println(o.Generation, found, "distinct solutions")
+			return found == 92
 		})
-		for ii := range state {
-			copy(state[ii], pop[ii].Number.Data)
-		}
-		fmt.Println(pop[0].Fitness)
-		if pop[0].Fitness == 0 {
-			for x := 0; x < 8; x++ {
-				y := uint(0)
-				for yy := range 3 {
-					y <<= 1
-					if pop[0].Number.Data[3*x+yy] > 0 {
-						y |= 1
-					}
-				}
+		return
+	}
+	Run(ctx, optimizer, iterations, func(o *eda.Optimizer) bool {
+		best := o.Best()
+		fmt.Println(best.Fitness)
+		if best.Fitness == 0 {
+			for _, y := range codec.Decode(best.Genome) {
 				fmt.Printf("%d, ", y)
 			}
 			fmt.Println()
-			break
+			return true
 		}
-	}
-
+		return false
+	})
 }
@@ -6,27 +6,24 @@ package main
 
 import (
 	"compress/bzip2"
+	"context"
 	"fmt"
 	"io"
 	"math/rand"
 	"os"
-	"runtime"
 	"sort"
+
+	"github.com/pointlander/entity/eda"
+	"github.com/pointlander/entity/matrix"
 )
 
 // RNN is the rnn model
-func RNN() {
-	rng := rand.New(rand.NewSource(1))
-	type RNN struct {
-		Layer   Matrix[float32]
-		Bias    Matrix[float32]
-		Fitness float64
-	}
-
+func RNN(ctx context.Context) {
+	source := eda.NewSource(*FlagSeed)
+	rng := rand.New(source)
 	const (
 		size       = 256
 		width      = size*size + size
-		models     = width / 8
 		iterations = 256
 		population = 256
 	)
@@ -54,155 +51,73 @@ func RNN() {
 	}
 
 	if *FlagBuild {
-		state := make([][]float32, 8)
-		for i := range state {
-			for range width {
-				state[i] = append(state[i], float32(rng.NormFloat64()))
-			}
-		}
-		pop := make([]RNN, population)
 		text := []rune(string(data))
-		for i := 0; i < iterations; i++ {
-			graph := false //i == 0 || i == iterations-1
-			translate := make([]int, width)
-			for i := range translate {
-				translate[i] = i % models
-			}
-			rng.Shuffle(width, func(i, j int) {
-				translate[i], translate[j] = translate[j], translate[i]
-			})
-			var a, u [models]Matrix[float32]
-			done := make(chan bool, 8)
-			process := func(ii int, seed int64) {
-				rng := rand.New(rand.NewSource(seed))
-				s := make([][]float32, 8)
-				for iii := range state {
-					for iv, t := range translate {
-						if t == ii {
-							s[iii] = append(s[iii], state[iii][iv])
-						}
-					}
-				}
-				a[ii], _, u[ii] = NewMultiVariateGaussian(.0001, 1.0e-1, graph, false, rng, fmt.Sprintf("rnn_%d", i), 8, s)
-				done <- true
-			}
-			ii, flight, cpus := 0, 0, runtime.NumCPU()
-			for ii < models && flight < cpus {
-				go process(ii, rng.Int63())
-				flight++
-				ii++
-			}
-			for ii < models {
-				<-done
-				flight--
-
-				go process(ii, rng.Int63())
-				flight++
-				ii++
-			}
-			for range flight {
-				<-done
-			}
-
-			born := pop
-			if i > 0 {
-				born = pop[8:]
-			}
-			learn := func(ii int, seed int64) {
-				start := rng.Intn(len(text) - 1024)
-				end := start + 1024
+		fitness := func(g []float32, rng *rand.Rand) float64 {
+			start := rng.Intn(len(text) - 1024)
+			end := start + 1024
 
-				rng := rand.New(rand.NewSource(seed))
-				vector := NewMatrix[float32](width, 1)
-				vector.Data = make([]float32, width)
-				for iii := range a {
-					g := NewMatrix[float32](a[iii].Cols, 1)
-					for range a[iii].Cols {
-						g.Data = append(g.Data, float32(rng.NormFloat64()))
-					}
-					vec, index := a[iii].MulT(g).Add(u[iii]), 0
-					for iv, t := range translate {
-						if t == iii {
-							vector.Data[iv] = vec.Data[index]
-							index++
-						}
+			layer := matrix.NewMatrix(size, size, g[:size*size]...)
+			bias := matrix.NewMatrix(size, 1, g[size*size:width]...)
+			fitness := 0.0
+			input := matrix.NewMatrix[float32](size, 1)
+			input.Data = make([]float32, size)
+			for _, symbol := range text[start:end] {
+				input = layer.MulT(input).Add(bias).Sigmoid()
+				target := forward[symbol]
+				for iv := range len(forward) {
+					var diff float32
+					if iv == int(target) {
+						diff = input.Data[iv] - 1
+					} else {
+						diff = input.Data[iv] - 0
 					}
+					fitness += float64(diff * diff)
 				}
-				born[ii].Layer = NewMatrix(size, size, vector.Data[:size*size]...)
-				born[ii].Bias = NewMatrix(size, 1, vector.Data[size*size:width]...)
-				born[ii].Fitness = 0.0
-				input := NewMatrix[float32](size, 1)
-				input.Data = make([]float32, size)
-				for _, symbol := range text[start:end] {
-					input = born[ii].Layer.MulT(input).Add(born[ii].Bias).Sigmoid()
-					target := forward[symbol]
-					for iv := range len(forward) {
-						var diff float32
-						if iv == int(target) {
-							diff = input.Data[iv] - 1
-						} else {
-							diff = input.Data[iv] - 0
-						}
-						born[ii].Fitness += float64(diff * diff)
-					}
-					for iv := range input.Data[:len(forward)] {
-						input.Data[iv] = 0
-					}
-					input.Data[target] = 1
+				for iv := range input.Data[:len(forward)] {
+					input.Data[iv] = 0
 				}
-				done <- true
-			}
-			ii, flight, cpus = 0, 0, runtime.NumCPU()
-			for ii < len(born) && flight < cpus {
-				go learn(ii, rng.Int63())
-				flight++
-				ii++
+				input.Data[target] = 1
 			}
-			for ii < len(born) {
-				<-done
-				flight--
-
-				go learn(ii, rng.Int63())
-				flight++
-				ii++
-			}
-			for range flight {
-				<-done
-			}
-
-			sort.Slice(pop, func(i, j int) bool {
-				return pop[i].Fitness < pop[j].Fitness
-			})
-			for ii := range state {
-				copy(state[ii], pop[ii].Layer.Data)
-				copy(state[ii][size*size:], pop[ii].Bias.Data)
-			}
-			fmt.Println(pop[0].Fitness)
+			return fitness
 		}
+		optimizer := eda.NewOptimizer(rng, width, 8, population, 8, nil)
+		optimizer.Stochastic = fitness
+		optimizer.Name = "rnn"
+		optimizer.Source = source
+		// the fitness is a random window of the text, so the all time best is the luckiest
+		// sample and the model is the best of the last generation
+		Run(ctx, optimizer, iterations, func(o *eda.Optimizer) bool {
+			fmt.Println(o.Pop[0].Fitness)
+			return false
+		})
 
-		output, err := os.Create("model.bin")
+		if optimizer.Generation == 0 {
+			return
+		}
+		best := optimizer.Pop[0].Genome
+		output, err := os.Create(artifacts.Path("rnn_model.bin"))
 		if err != nil {
 			panic(err)
 		}
 		defer output.Close()
-		err = pop[0].Layer.Write(output)
+		err = matrix.NewMatrix(size, size, best[:size*size]...).Write(output)
 		if err != nil {
 			panic(err)
 		}
-		pop[0].Bias.Write(output)
+		err = matrix.NewMatrix(size, 1, best[size*size:width]...).Write(output)
 		if err != nil {
 			panic(err)
 		}
 		return
 	}
 
-	input, err := os.Open("model.bin")
+	input, err := os.Open(Model("rnn"))
 	if err != nil {
 		panic(err)
 	}
 	defer input.Close()
-	layer := NewMatrix[float32](size, size)
-	bias := NewMatrix[float32](size, 1)
+	layer := matrix.NewMatrix[float32](size, size)
+	bias := matrix.NewMatrix[float32](size, 1)
 	err = layer.Read(input)
 	if err != nil {
 		panic(err)
@@ -214,7 +129,7 @@ func RNN() {
 
 	{
 		prompt := []rune("What color is the sky?")
-		input := NewMatrix[float32](size, 1)
+		input := matrix.NewMatrix[float32](size, 1)
 		input.Data = make([]float32, size)
 		last := -1
 		type Result struct {
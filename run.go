@@ -0,0 +1,311 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/pointlander/entity/eda"
+	"github.com/pointlander/entity/matrix"
+)
+
+var (
+	// resumed is set once a checkpoint has been loaded so restarts begin fresh
+	resumed bool
+	// evaluations is the number of fitness evaluations over all runs
+	evaluations int
+	// metrics writes the per generation metrics when set
+	metrics *eda.MetricsWriter
+	// evaluator evaluates the offspring in external processes when set
+	evaluator eda.Evaluator
+)
+
+// newSampler creates the search distribution of the -sampler flag, nil is the default
+func newSampler() eda.Sampler {
+	switch *FlagSampler {
+	case "gaussian":
+		if *FlagLearningRate > 0 || *FlagRanked {
+			return &eda.Partitioned{Rate: *FlagLearningRate, Ranked: *FlagRanked}
+		}
+		return nil
+	case "network":
+		return eda.NewNetwork()
+	case "cma":
+		return eda.NewCMA()
+	case "nes":
+		return eda.NewNES()
+	}
+	panic(fmt.Errorf("unknown sampler %s", *FlagSampler))
+}
+
+// Run runs an optimizer mode with the command line options applied
+func Run(ctx context.Context, optimizer *eda.Optimizer, iterations int, callback func(o *eda.Optimizer) bool) error {
+	name := optimizer.Name
+	if spec, ok := experiment.Modes[name]; ok {
+		iterations = spec.Apply(optimizer, iterations)
+	}
+	optimizer.Linkage = *FlagLinkage
+	if evaluator != nil {
+		optimizer.Evaluator = evaluator
+	}
+	if sampler := newSampler(); sampler != nil {
+		optimizer.Sampler = sampler
+	}
+	if *FlagRestart != "" {
+		strategies := map[string]int{
+			"none":   eda.RestartNone,
+			"random": eda.RestartRandom,
+			"ipop":   eda.RestartIPOP,
+		}
+		strategy, ok := strategies[*FlagRestart]
+		if !ok {
+			panic(fmt.Errorf("unknown restart strategy %s", *FlagRestart))
+		}
+		optimizer.Restart = eda.Restart{
+			Strategy:      strategy,
+			Patience:      *FlagPatience,
+			MinTrace:      *FlagMinTrace,
+			MinDiversity:  *FlagMinDiversity,
+			MaxPopulation: *FlagMaxPopulation,
+		}
+	}
+	if *FlagNiching != "" {
+		schemes := map[string]int{
+			"none":        eda.NichingNone,
+			"clearing":    eda.NichingClearing,
+			"replacement": eda.NichingReplacement,
+			"sharing":     eda.NichingSharing,
+		}
+		scheme, ok := schemes[*FlagNiching]
+		if !ok {
+			panic(fmt.Errorf("unknown niching scheme %s", *FlagNiching))
+		}
+		optimizer.Niching.Scheme = scheme
+		optimizer.Niching.Radius = *FlagRadius
+		optimizer.Niching.Capacity = *FlagCapacity
+	}
+	optimizer.Operators = eda.Operators{
+		Uniform:  *FlagUniform,
+		Block:    *FlagBlock,
+		Gaussian: *FlagMutate,
+		Flip:     *FlagFlip,
+		Sigma:    *FlagSigma,
+		Rate:     *FlagRate,
+	}
+	operators := *FlagUniform+*FlagBlock+*FlagMutate+*FlagFlip > 0
+	optimizer.Noise = eda.Noise{
+		Samples:    *FlagSamples,
+		Reevaluate: *FlagReevaluate,
+		Racing:     *FlagRacing,
+		Common:     *FlagCommon,
+	}
+	if *FlagSurrogate != "" {
+		surrogates := map[string]eda.Surrogate{
+			"knn": eda.KNN{K: *FlagNeighbors},
+			"gp":  eda.GP{Noise: 1e-3},
+		}
+		surrogate, ok := surrogates[*FlagSurrogate]
+		if !ok {
+			panic(fmt.Errorf("unknown surrogate %s", *FlagSurrogate))
+		}
+		optimizer.Screening = eda.Screening{
+			Surrogate: surrogate,
+			Fraction:  *FlagScreen,
+			Memory:    *FlagMemory,
+		}
+	}
+	optimizer.OnRestart = func(o *eda.Optimizer, reason string) {
+		fmt.Println("restart", o.Restarts, "of", o.Name, "at generation", o.Generation, "with population", o.Population, "because", reason)
+	}
+	if *FlagAdapt > 0 {
+		optimizer.Adaptation = eda.Adaptation{
+			Window:        *FlagAdapt,
+			MinPopulation: *FlagMinPopulation,
+			MaxPopulation: *FlagMaxPopulation,
+			MinRatio:      *FlagMinRatio,
+			MaxRatio:      *FlagMaxRatio,
+			Factor:        *FlagGrowth,
+			Progress:      *FlagProgress,
+		}
+	}
+	optimizer.OnAdapt = func(o *eda.Optimizer, reason string) {
+		fmt.Println("adapt", o.Name, "at generation", o.Generation, "to population", o.Population, "and cut", o.Cut, "because", reason)
+	}
+	artifacts.Resolve(optimizer, iterations)
+	var history *eda.Plot
+	if *FlagPlot {
+		history = &eda.Plot{Name: name}
+	}
+
+	optimizers := []*eda.Optimizer{optimizer}
+	var islands *eda.Islands
+	if *FlagIslands > 1 {
+		topologies := map[string]int{
+			"ring": eda.TopologyRing,
+			"full": eda.TopologyFull,
+		}
+		topology, ok := topologies[*FlagTopology]
+		if !ok {
+			panic(fmt.Errorf("unknown topology %s", *FlagTopology))
+		}
+		islands = eda.NewIslands(optimizer, *FlagIslands, newSampler)
+		islands.Topology = topology
+		islands.Interval = *FlagMigration
+		islands.Migrants = *FlagMigrants
+		optimizers = islands.Islands
+	}
+	// the islands are saved together with their migrations
+	checkpoint := artifacts.Path(fmt.Sprintf("%s.checkpoint", name))
+	if *FlagResume && !resumed {
+		resumed = true
+		var err error
+		if islands != nil {
+			err = islands.Load(checkpoint)
+		} else {
+			err = optimizer.Load(checkpoint)
+		}
+		if err != nil {
+			panic(err)
+		}
+		for _, o := range optimizers {
+			fmt.Println("resuming", o.Name, "at generation", o.Generation)
+		}
+	}
+	start := 0
+	for _, o := range optimizers {
+		start += o.Evals
+	}
+	if *FlagMaxEvals > 0 {
+		if islands != nil {
+			islands.MaxEvals = start + *FlagMaxEvals - evaluations
+		} else {
+			optimizer.MaxEvals = start + *FlagMaxEvals - evaluations
+		}
+	}
+	save := func() error {
+		if islands != nil {
+			return islands.Save(checkpoint)
+		}
+		return optimizer.Save(checkpoint)
+	}
+	totals := make([]eda.OperatorMetrics, eda.OperatorTotal)
+	step := func(o *eda.Optimizer) bool {
+		for _, o := range optimizers {
+			for i, op := range o.Metrics.Operators {
+				totals[i].Name = op.Name
+				totals[i].Offspring += op.Offspring
+				totals[i].Elites += op.Elites
+				totals[i].Improvements += op.Improvements
+			}
+		}
+		if metrics != nil {
+			for _, o := range optimizers {
+				err := metrics.Write(o.Metrics)
+				if err != nil {
+					panic(err)
+				}
+			}
+		}
+		if history != nil {
+			history.Record(o)
+		}
+		if *FlagSurrogate != "" {
+			for _, o := range optimizers {
+				fmt.Println("surrogate", o.Name, "generation", o.Generation, "rank correlation", o.Metrics.Surrogate)
+			}
+		}
+		stop := callback(o)
+		if *FlagCheckpoint > 0 && (stop || o.Generation%*FlagCheckpoint == 0 || o.Generation == iterations) {
+			err := save()
+			if err != nil {
+				panic(err)
+			}
+		}
+		return stop
+	}
+	var err error
+	if islands != nil {
+		err = islands.Run(ctx, iterations, func(is *eda.Islands) bool {
+			return step(is.Fittest())
+		})
+		for _, o := range islands.Islands {
+			fmt.Println("island", o.Name, "best", o.Best().Fitness, "restarts", o.Restarts)
+		}
+		fmt.Println(islands.Migrations, "migrations")
+		optimizer = islands.Fittest()
+	} else {
+		err = optimizer.Run(ctx, iterations, step)
+	}
+	if operators {
+		fmt.Printf("%-10s %10s %10s %13s\n", "operator", "offspring", "elites", "improvements")
+		for _, op := range totals {
+			fmt.Printf("%-10s %10d %10d %13d\n", op.Name, op.Offspring, op.Elites, op.Improvements)
+		}
+	}
+	for _, o := range optimizers {
+		evaluations += o.Evals
+	}
+	evaluations -= start
+	last = optimizer
+	if history != nil && len(history.Best) > 0 {
+		e := history.Fitness(artifacts.Path(fmt.Sprintf("%s_fitness.png", name)))
+		if e != nil {
+			panic(e)
+		}
+		e = history.Animate(artifacts.Path(fmt.Sprintf("%s_elites.gif", name)))
+		if e != nil {
+			panic(e)
+		}
+	}
+	if optimizer.Objectives != nil {
+		output, e := os.Create(artifacts.Path(fmt.Sprintf("%s_front.csv", name)))
+		if e != nil {
+			panic(e)
+		}
+		front := optimizer.Front()
+		e = eda.WriteFront(output, front)
+		if e != nil {
+			panic(e)
+		}
+		output.Close()
+		fmt.Println("pareto front of", len(front), "individuals")
+	}
+	if optimizer.Linkage {
+		for i, group := range optimizer.Groups() {
+			fmt.Println("linkage", i, group)
+		}
+	}
+	if err != nil {
+		fmt.Println("stopping", name, "at generation", optimizer.Generation, "after", optimizer.Evals, "evaluations:", err)
+	}
+	if optimizer.Generation == 0 {
+		return err
+	}
+	best := optimizer.Best()
+	output, e := os.Create(artifacts.Path(fmt.Sprintf("%s_best.bin", name)))
+	if e != nil {
+		panic(e)
+	}
+	e = matrix.NewMatrix(optimizer.Width, 1, best.Genome...).Write(output)
+	if e != nil {
+		panic(e)
+	}
+	e = output.Close()
+	if e != nil {
+		panic(e)
+	}
+	if err == nil {
+		return nil
+	}
+
+	fmt.Println("best", best.Fitness)
+	e = save()
+	if e != nil {
+		panic(e)
+	}
+	return err
+}
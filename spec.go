@@ -0,0 +1,214 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pointlander/entity/eda"
+)
+
+// Spec are the optimizer settings of a mode, zero values keep the defaults of the mode
+type Spec struct {
+	Population int     `json:"population,omitempty"`
+	Cut        int     `json:"cut,omitempty"`
+	Iterations int     `json:"iterations,omitempty"`
+	Partition  int     `json:"partition,omitempty"`
+	Cutoff     float64 `json:"cutoff,omitempty"`
+	Eta        float64 `json:"eta,omitempty"`
+}
+
+// Set sets the named setting
+func (s *Spec) Set(name string, value float64) error {
+	switch name {
+	case "population":
+		s.Population = int(value)
+	case "cut":
+		s.Cut = int(value)
+	case "iterations":
+		s.Iterations = int(value)
+	case "partition":
+		s.Partition = int(value)
+	case "cutoff":
+		s.Cutoff = value
+	case "eta":
+		s.Eta = value
+	default:
+		return fmt.Errorf("unknown setting %s", name)
+	}
+	return nil
+}
+
+// Apply applies the spec to the optimizer and returns the number of iterations
+func (s Spec) Apply(optimizer *eda.Optimizer, iterations int) int {
+	if s.Population > 0 || s.Cut > 0 {
+		population, cut := optimizer.Population, optimizer.Cut
+		if s.Population > 0 {
+			population = s.Population
+		}
+		if s.Cut > 0 {
+			cut = s.Cut
+		}
+		if cut > population {
+			panic(fmt.Errorf("the cut %d is larger than the population %d", cut, population))
+		}
+		optimizer.Resize(population, cut)
+	}
+	if s.Iterations > 0 {
+		iterations = s.Iterations
+	}
+	if s.Partition > 0 {
+		optimizer.Partition = s.Partition
+	}
+	if s.Cutoff != 0 {
+		optimizer.Cutoff = s.Cutoff
+	}
+	if s.Eta != 0 {
+		optimizer.Eta = s.Eta
+	}
+	return iterations
+}
+
+// Sweep is a grid of settings run across seeds for a mode
+type Sweep struct {
+	// Mode is the mode to sweep
+	Mode string `json:"mode"`
+	// Seeds is the number of seeds of each configuration
+	Seeds int `json:"seeds"`
+	// Grid are the values of each setting
+	Grid map[string][]float64 `json:"grid"`
+}
+
+// Experiment is an experiment specification file
+type Experiment struct {
+	// Modes are the settings of each mode by optimizer name
+	Modes map[string]Spec `json:"modes"`
+	// Sweep is the optional sweep
+	Sweep *Sweep `json:"sweep,omitempty"`
+}
+
+var (
+	// experiment is the loaded experiment specification
+	experiment Experiment
+	// last is the optimizer of the last run
+	last *eda.Optimizer
+)
+
+// Modes are the optimizer modes by name
+var Modes = map[string]func(ctx context.Context){
+	"rnn":         RNN,
+	"factor":      Factor,
+	"queens":      Queens,
+	"bf":          BF,
+	"ff":          FF,
+	"transformer": T,
+	"entropy":     Entropy,
+}
+
+// LoadExperiment loads an experiment specification file
+func LoadExperiment(name string) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		panic(err)
+	}
+	err = json.Unmarshal(data, &experiment)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// RunSweep runs each configuration of the sweep grid across seeds and prints a summary table
+func RunSweep(ctx context.Context) {
+	sweep := experiment.Sweep
+	if sweep == nil {
+		panic("the experiment has no sweep")
+	}
+	mode, ok := Modes[sweep.Mode]
+	if !ok {
+		panic(fmt.Errorf("unknown mode %s", sweep.Mode))
+	}
+	seeds := max(sweep.Seeds, 1)
+	names := make([]string, 0, len(sweep.Grid))
+	for name := range sweep.Grid {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	configurations := [][]float64{{}}
+	for _, name := range names {
+		next := [][]float64{}
+		for _, configuration := range configurations {
+			for _, value := range sweep.Grid[name] {
+				next = append(next, append(append([]float64{}, configuration...), value))
+			}
+		}
+		configurations = next
+	}
+
+	type Summary struct {
+		Configuration string
+		Fitness       []float64
+		Runtime       []time.Duration
+	}
+	base, seed := experiment.Modes[sweep.Mode], *FlagSeed
+	summaries := []Summary{}
+	for _, configuration := range configurations {
+		spec, settings := base, []string{}
+		for i, name := range names {
+			err := spec.Set(name, configuration[i])
+			if err != nil {
+				panic(err)
+			}
+			settings = append(settings, fmt.Sprintf("%s=%g", name, configuration[i]))
+		}
+		if experiment.Modes == nil {
+			experiment.Modes = make(map[string]Spec)
+		}
+		experiment.Modes[sweep.Mode] = spec
+		summary := Summary{Configuration: strings.Join(settings, " ")}
+		for s := range seeds {
+			*FlagSeed = seed + int64(s)
+			fmt.Println("sweep", summary.Configuration, "seed", *FlagSeed)
+			evaluations, last = 0, nil
+			start := time.Now()
+			mode(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			summary.Runtime = append(summary.Runtime, time.Since(start))
+			if last != nil {
+				summary.Fitness = append(summary.Fitness, last.Best().Fitness)
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+
+	fmt.Println()
+	fmt.Printf("%-40s %5s %13s %13s %13s %13s\n", "configuration", "seeds", "mean fitness", "best fitness", "worst fitness", "mean runtime")
+	for _, summary := range summaries {
+		mean, best, worst := 0.0, 0.0, 0.0
+		for i, fitness := range summary.Fitness {
+			mean += fitness / float64(len(summary.Fitness))
+			if i == 0 || fitness < best {
+				best = fitness
+			}
+			if i == 0 || fitness > worst {
+				worst = fitness
+			}
+		}
+		runtime := time.Duration(0)
+		for _, r := range summary.Runtime {
+			runtime += r / time.Duration(len(summary.Runtime))
+		}
+		fmt.Printf("%-40s %5d %13.6g %13.6g %13.6g %13s\n", summary.Configuration, len(summary.Fitness),
+			mean, best, worst, runtime.Round(time.Millisecond))
+	}
+}
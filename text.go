@@ -11,6 +11,9 @@ import (
 	"math"
 	"math/rand"
 	"os"
+
+	"github.com/pointlander/entity/eda"
+	"github.com/pointlander/entity/matrix"
 )
 
 // Text is the text model
@@ -39,15 +42,15 @@ func Text() {
 	}
 	length, datum := len(forward), []rune(string(data))
 
-	A, AI, u := make([]Matrix[float64], length), make([]Matrix[float64], length), make([]Matrix[float64], length)
+	A, AI, u := make([]matrix.Matrix[float64], length), make([]matrix.Matrix[float64], length), make([]matrix.Matrix[float64], length)
 	if *FlagBuild {
-		out, err := os.Create("model.bin")
+		out, err := os.Create(artifacts.Path("text_model.bin"))
 		if err != nil {
 			panic(err)
 		}
 		defer out.Close()
 
-		rng := rand.New(rand.NewSource(1))
+		rng := rand.New(rand.NewSource(*FlagSeed))
 		for i := range length {
 			vectors, index := make([][]float64, 0, 8), 8
 			for _, v := range datum[8:] {
@@ -60,7 +63,7 @@ func Text() {
 				}
 				index++
 			}
-			A[i], AI[i], u[i] = NewMultiVariateGaussian[float64](-1.0, 1.0e-1, true, true, rng, fmt.Sprintf("%d_text", i), length, vectors)
+			A[i], AI[i], u[i] = eda.NewMultiVariateGaussian[float64](-1.0, 1.0e-1, true, true, rng, fmt.Sprintf("%d_text", i), length, vectors)
 
 			buffer64 := make([]byte, 8)
 			for _, parameter := range u[i].Data {
@@ -106,14 +109,14 @@ func Text() {
 		return
 	}
 
-	input, err := os.Open("model.bin")
+	input, err := os.Open(Model("text"))
 	if err != nil {
 		panic(err)
 	}
 	defer input.Close()
 
 	for i := range length {
-		u[i] = NewMatrix[float64](length, 1)
+		u[i] = matrix.NewMatrix[float64](length, 1)
 		buffer64 := make([]byte, 8)
 		for range u[i].Rows {
 			for range u[i].Cols {
@@ -134,7 +137,7 @@ func Text() {
 				u[i].Data = append(u[i].Data, math.Float64frombits(value))
 			}
 		}
-		A[i] = NewMatrix[float64](length, length)
+		A[i] = matrix.NewMatrix[float64](length, length)
 		for range A[i].Rows {
 			for range A[i].Cols {
 				n, err := input.Read(buffer64)
@@ -154,7 +157,7 @@ func Text() {
 				A[i].Data = append(A[i].Data, math.Float64frombits(value))
 			}
 		}
-		AI[i] = NewMatrix[float64](length, length)
+		AI[i] = matrix.NewMatrix[float64](length, length)
 		for range AI[i].Rows {
 			for range AI[i].Cols {
 				n, err := input.Read(buffer64)
@@ -197,13 +200,13 @@ func Text() {
 		fmt.Println(i, count, total, float64(count)/float64(total))
 	}
 
-	rng := rand.New(rand.NewSource(1))
+	rng := rand.New(rand.NewSource(*FlagSeed))
 	grandPrompt, grandMax := []rune{}, 0
 	for range 33 {
 		prompt, grand := []rune("What is the meaning of life?"), 0
 		const iterations = 128
 		for range 8 {
-			vector := NewMatrix(length, 1, make([]float64, length)...)
+			vector := matrix.NewMatrix(length, 1, make([]float64, length)...)
 			for i := 1; i < 9; i++ {
 				vector.Data[forward[prompt[len(prompt)-i]]]++
 			}
@@ -12,6 +12,10 @@ import (
 )
 
 func Dot(x, y []float32) (z float32) {
-	vdot(unsafe.Pointer(&x[0]), unsafe.Pointer(&y[0]), unsafe.Pointer(uintptr(len(x))), unsafe.Pointer(&z))
+	// the kernel doesn't initialize its sum for vectors shorter than 4
+	if Scalar || len(x) < 4 {
+		return dot(x, y)
+	}
+	vdot(unsafe.Pointer(&x[0]), unsafe.Pointer(&y[0]), uintptr(len(x)), unsafe.Pointer(&z))
 	return z
 }
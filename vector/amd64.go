@@ -12,6 +12,10 @@ import (
 )
 
 func Dot(x, y []float32) (z float32) {
-	_mm256_dot(unsafe.Pointer(&x[0]), unsafe.Pointer(&y[0]), unsafe.Pointer(uintptr(len(x))), unsafe.Pointer(&z))
+	// the kernel doesn't initialize its sum for vectors shorter than 8
+	if Scalar || len(x) < 8 {
+		return dot(x, y)
+	}
+	_mm256_dot(unsafe.Pointer(&x[0]), unsafe.Pointer(&y[0]), uintptr(len(x)), unsafe.Pointer(&z))
 	return z
 }
@@ -43,3 +43,31 @@ func BenchmarkVectorDot(b *testing.B) {
 		Dot(x, y)
 	}
 }
+
+func TestScalar(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	x := make([]float32, Size)
+	for i := range x {
+		x[i] = float32(rng.NormFloat64())
+	}
+	y := make([]float32, Size)
+	for i := range y {
+		y[i] = float32(rng.NormFloat64())
+	}
+	Scalar = true
+	defer func() {
+		Scalar = false
+	}()
+	if a, b := Dot(x, y), dot(x, y); a != b {
+		t.Fatalf("scalar dot product should be exact %f != %f", a, b)
+	}
+}
+
+func TestDotShort(t *testing.T) {
+	x, y := []float32{1, 2, 3}, []float32{4, 5, 6}
+	for range 8 {
+		if z := Dot(x, y); z != 32 {
+			t.Fatalf("dot product of short vectors is broken %f != 32", z)
+		}
+	}
+}
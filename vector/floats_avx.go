@@ -6,4 +6,4 @@ package vector
 import "unsafe"
 
 //go:noescape
-func _mm256_dot(a, b, n, ret unsafe.Pointer)
+func _mm256_dot(a, b unsafe.Pointer, n uintptr, ret unsafe.Pointer)
@@ -6,4 +6,4 @@ package vector
 import "unsafe"
 
 //go:noescape
-func vdot(a, b, n, ret unsafe.Pointer)
+func vdot(a, b unsafe.Pointer, n uintptr, ret unsafe.Pointer)
@@ -4,9 +4,13 @@
 
 package vector
 
+// Scalar forces the scalar kernels so results are the same on every machine
+var Scalar bool
+
 func dot(x, y []float32) (z float32) {
 	for i := range x {
-		z += x[i] * y[i]
+		// the conversion prevents the compiler from fusing the multiply and add
+		z += float32(x[i] * y[i])
 	}
 	return z
 }
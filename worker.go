@@ -0,0 +1,67 @@
+// Copyright 2024 The Entity Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/pointlander/entity/eda"
+)
+
+// Worker evaluates the batches of a coordinator, the problems are the benchmark
+// problems and the fitness of each mode built from the same flags and seed as the coordinator
+func Worker(addr string) {
+	fitness := make(map[string]eda.Fitness)
+	benchmarks, err := Problems()
+	if err != nil {
+		panic(err)
+	}
+	for _, problem := range benchmarks {
+		fitness[problem.Name] = problem.Fitness
+	}
+	// the fitness of the modes are built when the coordinator first asks for them
+	modes := map[string]func() eda.Fitness{
+		"transformer": func() eda.Fitness {
+			// the transformer draws its samples first from the seed of the run
+			return Transformer(rand.New(eda.NewSource(*FlagSeed))).Fitness
+		},
+		"queens": QueensFitness,
+		"bf": func() eda.Fitness {
+			return func(g []float32) float64 {
+				_, fitness := BFFitness(g, nil)
+				return fitness
+			}
+		},
+		"ff": func() eda.Fitness {
+			iris := Load()
+			return func(g []float32) float64 {
+				_, fitness := FFFitness(iris, g)
+				return fitness
+			}
+		},
+		"entropy": func() eda.Fitness {
+			return func(g []float32) float64 {
+				_, fitness := EntropyFitness(g)
+				return fitness
+			}
+		},
+	}
+	problems := func(name string) (eda.Fitness, error) {
+		if f, ok := fitness[name]; ok {
+			return f, nil
+		}
+		if mode, ok := modes[name]; ok {
+			fitness[name] = mode()
+			return fitness[name], nil
+		}
+		return nil, fmt.Errorf("unknown problem %s", name)
+	}
+	fmt.Println("worker connecting to", addr)
+	err = eda.Work(addr, problems)
+	if err != nil {
+		panic(err)
+	}
+}